@@ -0,0 +1,525 @@
+package hsvimage
+
+import (
+	"image"
+	"math"
+)
+
+// clampUint8 rounds a float64 to the nearest integer and clamps it to
+// [0, 255].
+func clampUint8(x float64) uint8 {
+	switch {
+	case x <= 0.0:
+		return 0
+	case x >= 255.0:
+		return 255
+	default:
+		return uint8(math.Round(x))
+	}
+}
+
+// SaturationMaskedAdjust scales the saturation of each pixel by 1 +
+// (m/255)*maxBoost, where m is the mask's gray level at the same (absolute)
+// coordinates.  White mask regions therefore receive the full boost, black
+// regions (and regions outside the mask's bounds) receive none, and the
+// result is clamped to [0, 255].
+func (p *NHSVA) SaturationMaskedAdjust(mask *image.Gray, maxBoost float64) {
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			m := mask.GrayAt(x, y).Y
+			if m == 0 {
+				continue
+			}
+			scale := 1.0 + float64(m)/255.0*maxBoost
+			p.Pix[i+1] = clampUint8(float64(p.Pix[i+1]) * scale)
+		}
+	}
+}
+
+// InvertValue replaces each pixel's value with 255 minus that value, leaving
+// hue, saturation, and alpha untouched.  The effect is that of a photographic
+// negative in the value dimension.
+func (p *NHSVA) InvertValue() {
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			p.Pix[i+2] = 255 - p.Pix[i+2]
+		}
+	}
+}
+
+// AdjustValue adds delta, which may be negative, to each pixel's value,
+// clamping the result to [0, 255] rather than wrapping.  Hue, saturation, and
+// alpha are left untouched, so brightening or darkening does not shift colors.
+func (p *NHSVA) AdjustValue(delta int) {
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			p.Pix[i+2] = uint8(clampInt(int(p.Pix[i+2])+delta, 0, 255))
+		}
+	}
+}
+
+// GrayWorldCorrect reduces an image's overall color cast in place.  It is the
+// HSV analog of gray-world white balance: each non-transparent pixel's hue and
+// saturation are treated as a vector (with saturation as its length), the mean
+// of those vectors is taken as the image's color cast, and that mean is
+// subtracted from every pixel.  The resulting vectors determine each pixel's
+// new hue and saturation.  Value and alpha are left untouched.
+func (p *NHSVA) GrayWorldCorrect() {
+	// Compute the mean hue/saturation vector.
+	var cx, cy float64
+	n := 0
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			s := p.Pix[i : i+4 : i+4]
+			if s[3] == 0 {
+				continue
+			}
+			hr := hueDegrees(s[0]) * math.Pi / 180.0
+			sf := float64(s[1]) / 255.0
+			cx += sf * math.Cos(hr)
+			cy += sf * math.Sin(hr)
+			n++
+		}
+	}
+	if n == 0 {
+		return
+	}
+	cx /= float64(n)
+	cy /= float64(n)
+
+	// Subtract the mean vector from every pixel.
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			s := p.Pix[i : i+4 : i+4]
+			hr := hueDegrees(s[0]) * math.Pi / 180.0
+			sf := float64(s[1]) / 255.0
+			vx := sf*math.Cos(hr) - cx
+			vy := sf*math.Sin(hr) - cy
+			sf = math.Hypot(vx, vy)
+			if sf < 0.5/255.0 {
+				s[0], s[1] = 0, 0
+				continue
+			}
+			hd := math.Atan2(vy, vx) * 180.0 / math.Pi
+			if hd < 0.0 {
+				hd += 360.0
+			}
+			s[0] = hueFromDegrees(hd)
+			s[1] = clampUint8(sf * 255.0)
+		}
+	}
+}
+
+// ApplyGamma raises each pixel's value to the power 1/gamma, clamping the
+// result to [0, 1].  Hue, saturation, and alpha are left untouched.  A gamma
+// greater than 1 brightens the image; a gamma less than 1 darkens it.  Values
+// of 0 and 1 are unaffected by any gamma.
+func (p *NHSVAF64) ApplyGamma(gamma float64) {
+	if gamma == 1.0 {
+		return
+	}
+	inv := 1.0 / gamma
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			v := math.Max(0.0, math.Min(1.0, p.Pix[i+2]))
+			p.Pix[i+2] = math.Max(0.0, math.Min(1.0, math.Pow(v, inv)))
+		}
+	}
+}
+
+// CyclePalette rotates every pixel's hue by phase degrees, wrapping the
+// result into [0, 360).  Calling CyclePalette repeatedly with a small phase
+// produces a color-cycling animation.  Saturation, value, and alpha are left
+// untouched.
+func (p *NHSVAF64) CyclePalette(phase float64) {
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			p.Pix[i] = wrapDegrees(p.Pix[i] + phase)
+		}
+	}
+}
+
+// Hues, in degrees, toward which ShiftTemperature moves warm and cool shifts.
+const (
+	warmHue = 30.0  // Orange
+	coolHue = 220.0 // Sky blue
+)
+
+// ShiftTemperature adjusts an image's color temperature in the manner of a
+// white-balance slider.  A positive amount moves every pixel's hue toward
+// orange; a negative amount moves it toward blue.  Each hue moves along the
+// shorter arc by the fraction |amount| of its distance to the target, so hues
+// far from the target move more than hues already near it, and no hue
+// overshoots the target.  amount is clamped to [-1, 1], and an amount of 0
+// leaves the image unchanged.  Grays, whose hue is undefined, and saturation,
+// value, and alpha are left untouched.
+func (p *NHSVAF64) ShiftTemperature(amount float64) {
+	amount = math.Max(-1.0, math.Min(1.0, amount))
+	target := warmHue
+	if amount < 0.0 {
+		target, amount = coolHue, -amount
+	}
+	if amount == 0.0 {
+		return
+	}
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			s := p.Pix[i : i+2 : i+2]
+			if s[1] == 0.0 {
+				continue
+			}
+			s[0] = wrapDegrees(s[0] + amount*hueDelta(s[0], target))
+		}
+	}
+}
+
+// Vignette darkens an image toward its corners by multiplying each pixel's
+// value by 1 - strength·d², where d is the pixel's distance from the image's
+// center, normalized so that the corner pixels lie at a distance of 1.  The
+// center is therefore unchanged, and the corners' values are scaled by
+// 1 - strength.  Resulting values are clamped to [0, 1].  Hue, saturation,
+// and alpha are left untouched.
+func (p *NHSVAF64) Vignette(strength float64) {
+	cx := float64(p.Rect.Dx()-1) / 2.0
+	cy := float64(p.Rect.Dy()-1) / 2.0
+	d2max := cx*cx + cy*cy
+	if d2max == 0.0 {
+		return // A single pixel is its own center.
+	}
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		dy := float64(y-p.Rect.Min.Y) - cy
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			dx := float64(x-p.Rect.Min.X) - cx
+			f := 1.0 - strength*(dx*dx+dy*dy)/d2max
+			p.Pix[i+2] = math.Max(0.0, math.Min(1.0, p.Pix[i+2]*f))
+		}
+	}
+}
+
+// ClampInPlace forces every pixel's channels into their expected ranges by
+// wrapping hue into [0, 360) and clamping saturation, value, and alpha to
+// [0, 1].  Afterwards, every pixel's color satisfies hsvcolor.NHSVAF64's
+// InGamut predicate.
+func (p *NHSVAF64) ClampInPlace() {
+	clamp01 := func(x float64) float64 { return math.Max(0.0, math.Min(1.0, x)) }
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			s := p.Pix[i : i+4 : i+4]
+			s[0] = wrapDegrees(s[0])
+			s[1] = clamp01(s[1])
+			s[2] = clamp01(s[2])
+			s[3] = clamp01(s[3])
+		}
+	}
+}
+
+// PremultiplyValue scales every pixel's value by its alpha, converting the
+// image from the usual straight (non-premultiplied) representation to a
+// value-premultiplied representation suitable for filtering.  The image does
+// not record which representation it holds, because its pixels may be shared
+// with other images via SubImage; the caller is responsible for tracking the
+// state.  While an image is premultiplied, At and the other methods that
+// interpret colors will see darkened colors; call UnpremultiplyValue to
+// restore the straight representation.  Calling PremultiplyValue twice
+// multiplies by alpha twice.
+func (p *NHSVAF64) PremultiplyValue() {
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			p.Pix[i+2] *= p.Pix[i+3]
+		}
+	}
+}
+
+// UnpremultiplyValue reverses the effect of PremultiplyValue by dividing every
+// pixel's value by its alpha.  Pixels with zero alpha are given zero value.
+// UnpremultiplyValue should be called only on an image (or region) that the
+// caller previously premultiplied.
+func (p *NHSVAF64) UnpremultiplyValue() {
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			if a := p.Pix[i+3]; a == 0.0 {
+				p.Pix[i+2] = 0.0
+			} else {
+				p.Pix[i+2] /= a
+			}
+		}
+	}
+}
+
+// hueFalloff returns a weight in [0, 1] that is 1 for a hue (in degrees) at
+// center, falls off smoothly with angular distance, and reaches 0 at a
+// distance of width.
+func hueFalloff(h, center, width float64) float64 {
+	d := hueDistance(h, center)
+	if d >= width {
+		return 0.0
+	}
+	return 0.5 * (1.0 + math.Cos(math.Pi*d/width))
+}
+
+// LuminanceByHue multiplies the value of each pixel whose hue lies within
+// widthDeg degrees of centerDeg by valMul, with the effect tapering smoothly
+// to nothing as the hue approaches widthDeg away from centerDeg.  Pixels of
+// other hues, as well as gray pixels, are left alone.  Results are clamped to
+// [0, 255].
+func (p *NHSVA) LuminanceByHue(centerDeg, widthDeg, valMul float64) {
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			s := p.Pix[i : i+4 : i+4]
+			if s[1] == 0 {
+				continue
+			}
+			wt := hueFalloff(hueDegrees(s[0]), centerDeg, widthDeg)
+			if wt == 0.0 {
+				continue
+			}
+			s[2] = clampUint8(float64(s[2]) * (1.0 + wt*(valMul-1.0)))
+		}
+	}
+}
+
+// BoostSaturationInHueRange multiplies by factor the saturation of each pixel
+// whose hue lies within [lo, hi], clamping the result to [0, 255].  If lo > hi,
+// the range wraps around the color wheel through red.  Pixels of other hues
+// are left untouched, as are all hues, values, and alphas.
+func (p *NHSVA) BoostSaturationInHueRange(lo, hi uint8, factor float64) {
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			if hueInRange(p.Pix[i], lo, hi) {
+				p.Pix[i+1] = clampUint8(float64(p.Pix[i+1]) * factor)
+			}
+		}
+	}
+}
+
+// RemapHue recolors every pixel whose hue lies within tol of fromHue,
+// measured around the color wheel in either direction, by rotating its hue so
+// that fromHue maps to toHue.  A pixel's offset from fromHue is preserved, so
+// variations in hue within the matched band survive the recoloring.
+// Saturation, value, and alpha are left untouched, as are pixels of other
+// hues.
+func (p *NHSVA) RemapHue(fromHue, toHue, tol uint8) {
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			d := hueDiff8(fromHue, p.Pix[i])
+			if d < -int(tol) || d > int(tol) {
+				continue
+			}
+			p.Pix[i] = hueAdd8(toHue, d)
+		}
+	}
+}
+
+// Desaturate sets every pixel's saturation to zero, producing a grayscale
+// image whose gray levels are exactly the original values.  Hue, value, and
+// alpha are left untouched.
+func (p *NHSVA) Desaturate() {
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			p.Pix[i+1] = 0
+		}
+	}
+}
+
+// CleanGrays snaps near-gray pixels to exact gray, removing the faint color
+// fringing that conversion noise leaves in what should be neutral regions.
+// Every pixel whose saturation is less than satThreshold has both its
+// saturation and its (meaningless) hue set to zero, matching the hue that
+// hsvcolor.NHSVAModel assigns to grays.  Value and alpha are left untouched.
+func (p *NHSVA) CleanGrays(satThreshold uint8) {
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			s := p.Pix[i : i+2 : i+2]
+			if s[1] < satThreshold {
+				s[0], s[1] = 0, 0
+			}
+		}
+	}
+}
+
+// Complement rotates every pixel's hue halfway around the color wheel,
+// replacing each color with its complement.  Saturation, value, and alpha are
+// left untouched.
+func (p *NHSVA) Complement() {
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			p.Pix[i] += 128 // Wraps modulo 256.
+		}
+	}
+}
+
+// ApplyLUT remaps each pixel's hue, saturation, and value through the
+// corresponding 256-entry lookup table.  A nil table leaves its channel
+// untouched.  Alpha is always left untouched.
+func (p *NHSVA) ApplyLUT(hLUT, sLUT, vLUT *[256]uint8) {
+	for c, lut := range [...]*[256]uint8{hLUT, sLUT, vLUT} {
+		if lut == nil {
+			continue
+		}
+		for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+			i := p.PixOffset(p.Rect.Min.X, y) + c
+			for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+				p.Pix[i] = lut[p.Pix[i]]
+			}
+		}
+	}
+}
+
+// PosterizeHue snaps every pixel's hue to the nearest of levels evenly spaced
+// hues around the color wheel, starting from red (0), for a banded-color
+// effect.  A levels of 1 collapses all hues to red, and a levels of 6 yields
+// the primary and secondary colors.  levels is clamped to [1, 256].
+// Saturation, value, and alpha are left untouched.
+func (p *NHSVA) PosterizeHue(levels int) {
+	levels = clampInt(levels, 1, 256)
+	var lut [256]uint8
+	step := 255.0 / float64(levels)
+	for h := range lut {
+		k := int(math.Round(float64(h)/step)) % levels
+		lut[h] = clampUint8(float64(k) * step)
+	}
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			p.Pix[i] = lut[p.Pix[i]]
+		}
+	}
+}
+
+// SaturationSCurve applies an S-shaped tone curve to each pixel's saturation,
+// making strongly saturated pixels more saturated and weakly saturated pixels
+// less so for a punchy, film-like look.  The curve is the smoothstep function,
+// 3s²-2s³, which fixes saturations of 0, 1/2, and 1.  strength blends between
+// the identity (0) and the full curve (1); larger values exaggerate the
+// effect further.  Results are clamped to [0, 255].
+func (p *NHSVA) SaturationSCurve(strength float64) {
+	var lut [256]uint8
+	for i := range lut {
+		s := float64(i) / 255.0
+		c := s * s * (3.0 - 2.0*s)
+		lut[i] = clampUint8((s + strength*(c-s)) * 255.0)
+	}
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			p.Pix[i+1] = lut[p.Pix[i+1]]
+		}
+	}
+}
+
+// NormalizeValue linearly stretches the value channel so that the darkest
+// non-transparent pixel has a value of 0 and the brightest has a value of 1.
+// Hue, saturation, and alpha are left untouched.  If all non-transparent
+// pixels share the same value, the image is left unchanged.
+func (p *NHSVAF64) NormalizeValue() {
+	// Find the range of values across all non-transparent pixels.
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			s := p.Pix[i : i+4 : i+4]
+			if s[3] == 0.0 {
+				continue
+			}
+			lo = math.Min(lo, s[2])
+			hi = math.Max(hi, s[2])
+		}
+	}
+	if !(hi > lo) {
+		return
+	}
+
+	// Rescale every pixel's value.
+	scale := 1.0 / (hi - lo)
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			v := (p.Pix[i+2] - lo) * scale
+			p.Pix[i+2] = math.Max(0.0, math.Min(1.0, v))
+		}
+	}
+}
+
+// These are the hue and saturation that ApplySepia assigns to every pixel.
+const (
+	sepiaHue        = 25  // Warm brown (about 35°)
+	sepiaSaturation = 100 // Moderate
+)
+
+// ApplySepia gives an image a classic sepia-toned look by setting every
+// pixel's hue to a warm brown and its saturation to a moderate, fixed level.
+// Value and alpha are left untouched.
+func (p *NHSVA) ApplySepia() {
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			p.Pix[i] = sepiaHue
+			p.Pix[i+1] = sepiaSaturation
+		}
+	}
+}
+
+// ChromaKey makes transparent every pixel whose hue lies within hueTol of
+// targetHue, measured around the color wheel in either direction, and whose
+// saturation is at least minSat.  All other pixels are left untouched.  For
+// example, ChromaKey(85, 20, 80) removes a green-screen background.
+func (p *NHSVA) ChromaKey(targetHue, hueTol, minSat uint8) {
+	p.opaqueHint = false
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			s := p.Pix[i : i+4 : i+4]
+			if s[1] < minSat {
+				continue
+			}
+			if d := hueDiff8(targetHue, s[0]); d >= -int(hueTol) && d <= int(hueTol) {
+				s[3] = 0
+			}
+		}
+	}
+}
+
+// MultiplyAlpha scales every pixel's alpha by factor, rounding and clamping
+// the result to [0, 255], to fade an entire layer in or out.  Hue,
+// saturation, and value are left untouched.
+func (p *NHSVA) MultiplyAlpha(factor float64) {
+	p.opaqueHint = false
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			p.Pix[i+3] = clampUint8(float64(p.Pix[i+3]) * factor)
+		}
+	}
+}
+
+// SetGlobalAlpha sets every pixel's alpha to a.  Hue, saturation, and value
+// are left untouched.
+func (p *NHSVA) SetGlobalAlpha(a uint8) {
+	if a != 0xff {
+		p.opaqueHint = false
+	}
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			p.Pix[i+3] = a
+		}
+	}
+}
@@ -0,0 +1,819 @@
+// This file tests in-place adjustments to HSV images.
+
+package hsvimage
+
+import (
+	"bytes"
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// TestSaturationMaskedAdjust confirms that a mask's gray level controls the
+// fraction of the saturation boost that is applied.
+func TestSaturationMaskedAdjust(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 3, 1))
+	for x := 0; x < 3; x++ {
+		img.SetNHSVA(x, 0, hsvcolor.NHSVA{H: 10, S: 100, V: 200, A: 255})
+	}
+	mask := image.NewGray(image.Rect(0, 0, 3, 1))
+	mask.SetGray(0, 0, color.Gray{Y: 0})
+	mask.SetGray(1, 0, color.Gray{Y: 255})
+	mask.SetGray(2, 0, color.Gray{Y: 128})
+	img.SaturationMaskedAdjust(mask, 1.0)
+	expected := []uint8{100, 200, 150}
+	for x, s := range expected {
+		c := img.NHSVAAt(x, 0)
+		if c.S != s || c.H != 10 || c.V != 200 || c.A != 255 {
+			t.Fatalf("Expected saturation %d but saw %v at (%d, 0)", s, c, x)
+		}
+	}
+}
+
+// TestInvertValue confirms that inverting value twice is the identity and
+// that mid-gray maps consistently.
+func TestInvertValue(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: uint8(x * 16), S: uint8(y * 16), V: uint8(x*16 + y), A: 255})
+		}
+	}
+	orig := make([]uint8, len(img.Pix))
+	copy(orig, img.Pix)
+
+	// Invert a sub-image and ensure only its pixels changed.
+	sub := img.SubImage(image.Rect(4, 4, 8, 8)).(*NHSVA)
+	sub.InvertValue()
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			i := img.PixOffset(x, y)
+			v := orig[i+2]
+			if (image.Point{x, y}).In(sub.Rect) {
+				v = 255 - v
+			}
+			c := img.NHSVAAt(x, y)
+			if c.V != v || c.H != orig[i] || c.S != orig[i+1] || c.A != orig[i+3] {
+				t.Fatalf("Expected value %d but saw %v at (%d, %d)", v, c, x, y)
+			}
+		}
+	}
+
+	// Invert twice and confirm we recover the original.
+	sub.InvertValue()
+	for i := range orig {
+		if img.Pix[i] != orig[i] {
+			t.Fatalf("Double inversion changed Pix[%d] from %d to %d", i, orig[i], img.Pix[i])
+		}
+	}
+
+	// Check mid-gray.
+	gray := NewNHSVA(image.Rect(0, 0, 1, 1))
+	gray.SetNHSVA(0, 0, hsvcolor.NHSVA{V: 128, A: 255})
+	gray.InvertValue()
+	if v := gray.NHSVAAt(0, 0).V; v != 127 {
+		t.Fatalf("Expected mid-gray to invert to 127 but saw %d", v)
+	}
+}
+
+// colorCast returns the magnitude of the mean hue/saturation vector of an
+// NHSVA image.
+func colorCast(img *NHSVA) float64 {
+	var cx, cy float64
+	n := 0
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			c := img.NHSVAAt(x, y)
+			hr := hueDegrees(c.H) * math.Pi / 180.0
+			cx += float64(c.S) / 255.0 * math.Cos(hr)
+			cy += float64(c.S) / 255.0 * math.Sin(hr)
+			n++
+		}
+	}
+	return math.Hypot(cx, cy) / float64(n)
+}
+
+// TestAdjustValue confirms that AdjustValue adds an offset to value, clamping
+// at both ends, and touches nothing else.
+func TestAdjustValue(t *testing.T) {
+	for _, delta := range []int{0, 40, -40, 300, -300} {
+		img := NewNHSVA(image.Rect(0, 0, 256, 3))
+		for y := 0; y < 3; y++ {
+			for x := 0; x < 256; x++ {
+				img.SetNHSVA(x, y, hsvcolor.NHSVA{H: 200, S: 150, V: uint8(x), A: 100})
+			}
+		}
+		img.SubImage(image.Rect(0, 1, 256, 2)).(*NHSVA).AdjustValue(delta)
+		for y := 0; y < 3; y++ {
+			for x := 0; x < 256; x++ {
+				v := x
+				if y == 1 {
+					v = clampInt(x+delta, 0, 255)
+				}
+				exp := hsvcolor.NHSVA{H: 200, S: 150, V: uint8(v), A: 100}
+				if c := img.NHSVAAt(x, y); c != exp {
+					t.Fatalf("%+d: Expected %v but saw %v at (%d, %d)", delta, exp, c, x, y)
+				}
+			}
+		}
+	}
+}
+
+// TestGrayWorldCorrect confirms that a uniform hue cast is reduced.
+func TestGrayWorldCorrect(t *testing.T) {
+	// Draw pixels of evenly spaced hues plus an orange cast.
+	img := NewNHSVA(image.Rect(0, 0, 12, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 12; x++ {
+			h := uint8(x * 255 / 12)
+			s := uint8(100)
+			if x%3 == 0 {
+				h, s = 20, 200 // Cast
+			}
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: h, S: s, V: uint8(100 + y*20), A: 255})
+		}
+	}
+	before := colorCast(img)
+	img.GrayWorldCorrect()
+	after := colorCast(img)
+	if after >= before/4 {
+		t.Fatalf("Expected the color cast to shrink from %.4f but saw %.4f", before, after)
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 12; x++ {
+			c := img.NHSVAAt(x, y)
+			if c.V != uint8(100+y*20) || c.A != 255 {
+				t.Fatalf("Expected value and alpha to be unchanged but saw %v at (%d, %d)", c, x, y)
+			}
+		}
+	}
+
+	// A uniform, single-hue image should become gray.
+	uni := NewNHSVA(image.Rect(0, 0, 3, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			uni.SetNHSVA(x, y, hsvcolor.NHSVA{H: 60, S: 90, V: 150, A: 255})
+		}
+	}
+	uni.GrayWorldCorrect()
+	if c := uni.NHSVAAt(1, 1); c.S != 0 {
+		t.Fatalf("Expected a uniform image to become gray but saw %v", c)
+	}
+}
+
+// TestApplyGamma confirms that gamma correction brightens mid-values by the
+// expected amount and leaves the endpoints fixed.
+func TestApplyGamma(t *testing.T) {
+	img := NewNHSVAF64(image.Rect(0, 0, 3, 1))
+	img.SetNHSVAF64(0, 0, hsvcolor.NHSVAF64{H: 90.0, S: 0.5, V: 0.0, A: 1.0})
+	img.SetNHSVAF64(1, 0, hsvcolor.NHSVAF64{H: 90.0, S: 0.5, V: 0.5, A: 1.0})
+	img.SetNHSVAF64(2, 0, hsvcolor.NHSVAF64{H: 90.0, S: 0.5, V: 1.0, A: 1.0})
+	img.ApplyGamma(1.0)
+	if v := img.NHSVAF64At(1, 0).V; v != 0.5 {
+		t.Fatalf("Expected a gamma of 1.0 to leave 0.5 alone but saw %.5f", v)
+	}
+	img.ApplyGamma(2.2)
+	expected := []float64{0.0, math.Pow(0.5, 1.0/2.2), 1.0}
+	for x, v := range expected {
+		c := img.NHSVAF64At(x, 0)
+		if math.Abs(c.V-v) > 1e-12 || c.H != 90.0 || c.S != 0.5 || c.A != 1.0 {
+			t.Fatalf("Expected value %.5f but saw %v at (%d, 0)", v, c, x)
+		}
+	}
+	if v := img.NHSVAF64At(1, 0).V; math.Abs(v-0.7297) > 0.0001 {
+		t.Fatalf("Expected a mid-value of about 0.7297 but saw %.5f", v)
+	}
+}
+
+// TestLuminanceByHue confirms that only pixels near the target hue are
+// brightened.
+func TestLuminanceByHue(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 4, 1))
+	img.SetNHSVA(0, 0, hsvcolor.NHSVA{H: 43, S: 255, V: 100, A: 255}) // Yellow
+	img.SetNHSVA(1, 0, hsvcolor.NHSVA{H: 0, S: 255, V: 100, A: 255})  // Red
+	img.SetNHSVA(2, 0, hsvcolor.NHSVA{H: 32, S: 255, V: 100, A: 255}) // Orange-yellow
+	img.SetNHSVA(3, 0, hsvcolor.NHSVA{H: 43, S: 0, V: 100, A: 255})   // Gray
+	img.LuminanceByHue(60.0, 30.0, 1.5)
+	if v := img.NHSVAAt(0, 0).V; v < 149 || v > 150 {
+		t.Fatalf("Expected yellow to be brightened to about 150 but saw %d", v)
+	}
+	if v := img.NHSVAAt(1, 0).V; v != 100 {
+		t.Fatalf("Expected red to be untouched but saw %d", v)
+	}
+	if v := img.NHSVAAt(2, 0).V; v <= 100 || v >= 149 {
+		t.Fatalf("Expected orange-yellow to be partially brightened but saw %d", v)
+	}
+	if v := img.NHSVAAt(3, 0).V; v != 100 {
+		t.Fatalf("Expected gray to be untouched but saw %d", v)
+	}
+}
+
+// TestBoostSaturationInHueRange confirms that only pixels within the hue
+// range have their saturation boosted, with clamping.
+func TestBoostSaturationInHueRange(t *testing.T) {
+	for _, rng := range [][2]uint8{{150, 180}, {240, 20}} {
+		lo, hi := rng[0], rng[1]
+		img := NewNHSVA(image.Rect(0, 0, 256, 2))
+		for x := 0; x < 256; x++ {
+			img.SetNHSVA(x, 0, hsvcolor.NHSVA{H: uint8(x), S: 100, V: 150, A: 255})
+			img.SetNHSVA(x, 1, hsvcolor.NHSVA{H: uint8(x), S: 200, V: 150, A: 255})
+		}
+		img.BoostSaturationInHueRange(lo, hi, 1.5)
+		for x := 0; x < 256; x++ {
+			h := uint8(x)
+			in := (lo <= hi && h >= lo && h <= hi) || (lo > hi && (h >= lo || h <= hi))
+			s0, s1 := uint8(100), uint8(200)
+			if in {
+				s0, s1 = 150, 255
+			}
+			if c := img.NHSVAAt(x, 0); c != (hsvcolor.NHSVA{H: h, S: s0, V: 150, A: 255}) {
+				t.Fatalf("[%d, %d]: Expected saturation %d but saw %v at (%d, 0)", lo, hi, s0, c, x)
+			}
+			if c := img.NHSVAAt(x, 1); c != (hsvcolor.NHSVA{H: h, S: s1, V: 150, A: 255}) {
+				t.Fatalf("[%d, %d]: Expected saturation %d but saw %v at (%d, 1)", lo, hi, s1, c, x)
+			}
+		}
+	}
+}
+
+// TestRemapHue confirms that RemapHue recolors a red patch blue, preserving
+// hue offsets, while leaving a green patch alone.
+func TestRemapHue(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 10, 2))
+	reds := []uint8{250, 255, 0, 3, 6}
+	for x := 0; x < 10; x++ {
+		h := uint8(80 + x) // Green
+		if x < 5 {
+			h = reds[x]
+		}
+		img.SetNHSVA(x, 0, hsvcolor.NHSVA{H: h, S: 200, V: 180, A: 255})
+		img.SetNHSVA(x, 1, hsvcolor.NHSVA{H: h + 128, S: 200, V: 180, A: 255}) // Complements
+	}
+	img.RemapHue(0, 170, 8)
+	blues := []uint8{165, 170, 170, 173, 176} // Hue 255 is the same red as 0.
+	for x := 0; x < 10; x++ {
+		h := uint8(80 + x)
+		if x < 5 {
+			h = blues[x]
+		}
+		if c := img.NHSVAAt(x, 0); c != (hsvcolor.NHSVA{H: h, S: 200, V: 180, A: 255}) {
+			t.Fatalf("Expected hue %d but saw %v at (%d, 0)", h, c, x)
+		}
+		orig := uint8(80+x) + 128
+		if x < 5 {
+			orig = reds[x] + 128
+		}
+		if c := img.NHSVAAt(x, 1); c.H != orig {
+			t.Fatalf("Expected hue %d to be left alone but saw %v at (%d, 1)", orig, c, x)
+		}
+	}
+
+	// Remapping across red should wrap correctly.
+	wrap := NewNHSVA(image.Rect(0, 0, 1, 1))
+	wrap.SetNHSVA(0, 0, hsvcolor.NHSVA{H: 88, S: 255, V: 255, A: 255})
+	wrap.RemapHue(85, 254, 5)
+	if h := wrap.NHSVAAt(0, 0).H; h != 2 {
+		t.Fatalf("Expected hue 2 but saw %d", h)
+	}
+}
+
+// TestDesaturate confirms that desaturation produces neutral grays whose
+// levels match the original values.
+func TestDesaturate(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: uint8(x * 16), S: uint8(255 - y), V: uint8(x*16 + y), A: 255})
+		}
+	}
+	sub := img.SubImage(image.Rect(0, 0, 16, 8)).(*NHSVA)
+	sub.Desaturate()
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			c := img.NHSVAAt(x, y)
+			if y >= 8 {
+				if c.S != uint8(255-y) {
+					t.Fatalf("Expected (%d, %d), outside the sub-image, to be untouched but saw %v", x, y, c)
+				}
+				continue
+			}
+			v16 := uint32(x*16+y) * 0x101
+			r, g, b, a := c.RGBA()
+			if c.H != uint8(x*16) || r != v16 || g != v16 || b != v16 || a != 0xffff {
+				t.Fatalf("Expected gray level %d but saw %v = {%d, %d, %d, %d} at (%d, %d)", v16, c, r, g, b, a, x, y)
+			}
+		}
+	}
+}
+
+// TestCleanGrays confirms that CleanGrays neutralizes only weakly saturated
+// pixels and only within a sub-image.
+func TestCleanGrays(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 8, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 8; x++ {
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: uint8(x * 30), S: uint8(x * 2), V: 128, A: uint8(255 - x)})
+		}
+	}
+	img.SetNHSVA(7, 0, hsvcolor.NHSVA{H: 170, S: 200, V: 90, A: 255})
+	img.SubImage(image.Rect(0, 0, 8, 1)).(*NHSVA).CleanGrays(6)
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 8; x++ {
+			want := hsvcolor.NHSVA{H: uint8(x * 30), S: uint8(x * 2), V: 128, A: uint8(255 - x)}
+			switch {
+			case x == 7 && y == 0:
+				want = hsvcolor.NHSVA{H: 170, S: 200, V: 90, A: 255}
+			case y == 0 && x < 3:
+				want.H, want.S = 0, 0
+			}
+			if c := img.NHSVAAt(x, y); c != want {
+				t.Fatalf("Expected %v but saw %v at (%d, %d)", want, c, x, y)
+			}
+		}
+	}
+}
+
+// TestComplement confirms that complementing rotates hues by half the color
+// wheel within a sub-image only.
+func TestComplement(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: 0, S: 255, V: 255, A: 255})
+		}
+	}
+	img.SetNHSVA(0, 0, hsvcolor.NHSVA{H: 200, S: 100, V: 50, A: 25})
+	img.SubImage(image.Rect(0, 0, 2, 4)).(*NHSVA).Complement()
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			want := hsvcolor.NHSVA{H: 0, S: 255, V: 255, A: 255}
+			switch {
+			case x == 0 && y == 0:
+				want = hsvcolor.NHSVA{H: 72, S: 100, V: 50, A: 25}
+			case x < 2:
+				want.H = 128
+			}
+			if c := img.NHSVAAt(x, y); c != want {
+				t.Fatalf("Expected %v but saw %v at (%d, %d)", want, c, x, y)
+			}
+		}
+	}
+}
+
+// TestApplyLUT confirms that ApplyLUT remaps only the channels given non-nil
+// tables and only within the image's bounds.
+func TestApplyLUT(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: uint8(x * 16), S: uint8(y * 16), V: uint8(x*16 + y), A: uint8(255 - x)})
+		}
+	}
+	orig := NewNHSVA(img.Rect)
+	copy(orig.Pix, img.Pix)
+
+	// An identity table should change nothing.
+	var ident, invert [256]uint8
+	for i := range ident {
+		ident[i] = uint8(i)
+		invert[i] = uint8(255 - i)
+	}
+	img.ApplyLUT(&ident, &ident, &ident)
+	if !bytes.Equal(img.Pix, orig.Pix) {
+		t.Fatal("Expected an identity LUT to leave the image unchanged")
+	}
+
+	// An inverting value table should invert only the value channel of
+	// only the sub-image.
+	r := image.Rect(4, 4, 12, 12)
+	img.SubImage(r).(*NHSVA).ApplyLUT(nil, nil, &invert)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			exp := orig.NHSVAAt(x, y)
+			if (image.Point{x, y}).In(r) {
+				exp.V = 255 - exp.V
+			}
+			if c := img.NHSVAAt(x, y); c != exp {
+				t.Fatalf("Expected %v but saw %v at (%d, %d)", exp, c, x, y)
+			}
+		}
+	}
+}
+
+// TestPosterizeHue confirms that PosterizeHue snaps hues to evenly spaced
+// levels.
+func TestPosterizeHue(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 256, 3))
+	for x := 0; x < 256; x++ {
+		img.SetNHSVA(x, 1, hsvcolor.NHSVA{H: uint8(x), S: 200, V: 100, A: 255})
+	}
+	sub := img.SubImage(image.Rect(0, 1, 256, 2)).(*NHSVA)
+	sub.PosterizeHue(2)
+	for x := 0; x < 256; x++ {
+		c := img.NHSVAAt(x, 1)
+		if c.H != 0 && c.H != 128 {
+			t.Fatalf("Expected a hue of 0 or 128 but saw %d at (%d, 1)", c.H, x)
+		}
+		if c.S != 200 || c.V != 100 || c.A != 255 {
+			t.Fatalf("Expected only the hue to change but saw %v at (%d, 1)", c, x)
+		}
+		if c0 := img.NHSVAAt(x, 0); c0 != (hsvcolor.NHSVA{}) {
+			t.Fatalf("Expected a pixel outside the sub-image to be untouched but saw %v at (%d, 0)", c0, x)
+		}
+	}
+	if h := img.NHSVAAt(100, 1).H; h != 128 {
+		t.Fatalf("Expected hue 100 to snap to 128 but saw %d", h)
+	}
+	if h := img.NHSVAAt(250, 1).H; h != 0 {
+		t.Fatalf("Expected hue 250 to wrap to 0 but saw %d", h)
+	}
+
+	// Six levels should produce the primaries and secondaries.
+	rainbow := NewNHSVA(image.Rect(0, 0, 256, 1))
+	for x := 0; x < 256; x++ {
+		rainbow.SetNHSVA(x, 0, hsvcolor.NHSVA{H: uint8(x), S: 255, V: 255, A: 255})
+	}
+	rainbow.PosterizeHue(6)
+	want := map[uint8]bool{0: true, 43: true, 85: true, 128: true, 170: true, 213: true}
+	for x := 0; x < 256; x++ {
+		if h := rainbow.NHSVAAt(x, 0).H; !want[h] {
+			t.Fatalf("Expected a primary or secondary hue but saw %d at (%d, 0)", h, x)
+		}
+	}
+
+	// A single level should collapse everything to red.
+	rainbow.PosterizeHue(0)
+	for x := 0; x < 256; x++ {
+		if h := rainbow.NHSVAAt(x, 0).H; h != 0 {
+			t.Fatalf("Expected hue 0 but saw %d at (%d, 0)", h, x)
+		}
+	}
+}
+
+// TestSaturationSCurve confirms that the S-curve pushes saturations away from
+// the midpoint.
+func TestSaturationSCurve(t *testing.T) {
+	sats := []uint8{0, 40, 100, 128, 160, 220, 255}
+	img := NewNHSVA(image.Rect(0, 0, len(sats), 1))
+	for x, s := range sats {
+		img.SetNHSVA(x, 0, hsvcolor.NHSVA{H: 33, S: s, V: 123, A: 255})
+	}
+	img.SaturationSCurve(1.0)
+	for x, s0 := range sats {
+		c := img.NHSVAAt(x, 0)
+		s1 := c.S
+		switch {
+		case s0 == 0 || s0 == 255 || s0 == 128:
+			if s1 != s0 {
+				t.Fatalf("Expected saturation %d to be a fixed point but saw %d", s0, s1)
+			}
+		case s0 < 128:
+			if s1 >= s0 {
+				t.Fatalf("Expected saturation %d to decrease but saw %d", s0, s1)
+			}
+		default:
+			if s1 <= s0 {
+				t.Fatalf("Expected saturation %d to increase but saw %d", s0, s1)
+			}
+		}
+		if c.H != 33 || c.V != 123 || c.A != 255 {
+			t.Fatalf("Expected only saturation to change but saw %v", c)
+		}
+	}
+}
+
+// TestCyclePalette confirms that CyclePalette rotates hues with wraparound
+// and that a full cycle restores the original hues.
+func TestCyclePalette(t *testing.T) {
+	img := NewHSVGradient(image.Rect(0, 0, 36, 2), true)
+	orig := NewNHSVAF64(img.Rect)
+	copy(orig.Pix, img.Pix)
+	img.CyclePalette(350.0)
+	if h := img.NHSVAF64At(2, 0).H; math.Abs(h-10.0) > 1e-9 {
+		t.Fatalf("Expected hue 20 to wrap around to 10 but saw %.5f", h)
+	}
+	img.CyclePalette(-230.0)
+	img.CyclePalette(-120.0)
+	for k := 0; k < 360; k++ {
+		img.CyclePalette(1.0)
+	}
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 36; x++ {
+			a, b := orig.NHSVAF64At(x, y), img.NHSVAF64At(x, y)
+			if hueDistance(a.H, b.H) > 1e-9 || a.S != b.S || a.V != b.V || a.A != b.A {
+				t.Fatalf("Expected %v but saw %v at (%d, %d)", a, b, x, y)
+			}
+			if b.H < 0.0 || b.H >= 360.0 {
+				t.Fatalf("Expected a hue in [0, 360) but saw %.5f at (%d, %d)", b.H, x, y)
+			}
+		}
+	}
+}
+
+// TestShiftTemperature confirms that ShiftTemperature moves hues toward
+// orange or blue in proportion to their distance from the target.
+func TestShiftTemperature(t *testing.T) {
+	img := NewNHSVAF64(image.Rect(0, 0, 4, 1))
+	cs := []hsvcolor.NHSVAF64{
+		{H: 60.0, S: 1.0, V: 1.0, A: 1.0},  // Yellow
+		{H: 30.0, S: 0.5, V: 0.5, A: 1.0},  // Orange
+		{H: 350.0, S: 0.8, V: 0.2, A: 0.5}, // Red
+		{H: 200.0, S: 0.0, V: 0.7, A: 1.0}, // Gray
+	}
+	for x, c := range cs {
+		img.SetNHSVAF64(x, 0, c)
+	}
+
+	// A shift of 0 should be a no-op.
+	img.ShiftTemperature(0.0)
+	for x, c := range cs {
+		if got := img.NHSVAF64At(x, 0); got != c {
+			t.Fatalf("Expected %v but saw %v at (%d, 0)", c, got, x)
+		}
+	}
+
+	// A warm shift should move hues partway toward orange.
+	img.ShiftTemperature(0.5)
+	for x, h := range []float64{45.0, 30.0, 10.0, 200.0} {
+		got := img.NHSVAF64At(x, 0)
+		if math.Abs(got.H-h) > 1e-9 || got.S != cs[x].S || got.V != cs[x].V || got.A != cs[x].A {
+			t.Fatalf("Expected hue %.1f but saw %v at (%d, 0)", h, got, x)
+		}
+	}
+
+	// A full cool shift should move every colored hue to blue.
+	img.ShiftTemperature(-5.0)
+	for x, h := range []float64{coolHue, coolHue, coolHue, 200.0} {
+		if got := img.NHSVAF64At(x, 0).H; math.Abs(got-h) > 1e-9 {
+			t.Fatalf("Expected hue %.1f but saw %.5f at (%d, 0)", h, got, x)
+		}
+	}
+}
+
+// TestVignette confirms that Vignette leaves the center of an image unchanged
+// and darkens its corners in proportion to strength.
+func TestVignette(t *testing.T) {
+	for _, strength := range []float64{0.0, 0.25, 0.5, 1.0, 2.0} {
+		img := NewNHSVAF64(image.Rect(3, 4, 12, 11))
+		for y := 4; y < 11; y++ {
+			for x := 3; x < 12; x++ {
+				img.SetNHSVAF64(x, y, hsvcolor.NHSVAF64{H: float64(x * 20), S: 0.5, V: 0.8, A: 0.9})
+			}
+		}
+		img.Vignette(strength)
+		if c := img.NHSVAF64At(7, 7); c.V != 0.8 {
+			t.Fatalf("Expected the center value to remain 0.8 but saw %v", c)
+		}
+		exp := math.Max(0.0, 0.8*(1.0-strength))
+		for _, pt := range []image.Point{{3, 4}, {11, 4}, {3, 10}, {11, 10}} {
+			c := img.NHSVAF64At(pt.X, pt.Y)
+			if math.Abs(c.V-exp) > 1e-12 || c.H != float64(pt.X*20) || c.S != 0.5 || c.A != 0.9 {
+				t.Fatalf("Expected value %.3f but saw %v at %v with strength %.2f", exp, c, pt, strength)
+			}
+		}
+		if c, e := img.NHSVAF64At(5, 7), img.NHSVAF64At(3, 7); strength > 0.0 && !(c.V < 0.8 && e.V < c.V) {
+			t.Fatalf("Expected values to fall off with distance but saw %v and %v", c, e)
+		}
+	}
+}
+
+// TestClampInPlace confirms that ClampInPlace brings out-of-range channels
+// back into range.
+func TestClampInPlace(t *testing.T) {
+	in := []hsvcolor.NHSVAF64{
+		{H: 400.0, S: 1.5, V: -0.5, A: 2.0},
+		{H: -90.0, S: -1.0, V: 0.5, A: -0.25},
+		{H: 360.0, S: 0.5, V: 1.25, A: 1.0},
+		{H: -1e-20, S: 0.25, V: 0.75, A: 0.5},
+		{H: 123.0, S: 0.4, V: 0.6, A: 0.8},
+	}
+	out := []hsvcolor.NHSVAF64{
+		{H: 40.0, S: 1.0, V: 0.0, A: 1.0},
+		{H: 270.0, S: 0.0, V: 0.5, A: 0.0},
+		{H: 0.0, S: 0.5, V: 1.0, A: 1.0},
+		{H: 0.0, S: 0.25, V: 0.75, A: 0.5},
+		{H: 123.0, S: 0.4, V: 0.6, A: 0.8},
+	}
+	img := NewNHSVAF64(image.Rect(0, 0, len(in), 1))
+	for x, c := range in {
+		img.SetNHSVAF64(x, 0, c)
+	}
+	img.ClampInPlace()
+	for x, e := range out {
+		c := img.NHSVAF64At(x, 0)
+		if math.Abs(c.H-e.H) > 1e-9 || c.S != e.S || c.V != e.V || c.A != e.A {
+			t.Fatalf("Expected %v but saw %v at (%d, 0)", e, c, x)
+		}
+		if !c.InGamut() {
+			t.Fatalf("Expected %v to be in gamut at (%d, 0)", c, x)
+		}
+	}
+}
+
+// TestPremultiplyValue confirms that PremultiplyValue and UnpremultiplyValue
+// round-trip, including when applied to a sub-image.
+func TestPremultiplyValue(t *testing.T) {
+	alphas := []float64{0.0, 0.1, 0.25, 0.5, 0.9, 1.0}
+	img := NewNHSVAF64(image.Rect(0, 0, len(alphas), 1))
+	for x, a := range alphas {
+		img.SetNHSVAF64(x, 0, hsvcolor.NHSVAF64{H: 50.0, S: 0.5, V: 0.8, A: a})
+	}
+	img.PremultiplyValue()
+	for x, a := range alphas {
+		if v := img.NHSVAF64At(x, 0).V; math.Abs(v-0.8*a) > 1e-12 {
+			t.Fatalf("Expected value %.5f but saw %.5f at (%d, 0)", 0.8*a, v, x)
+		}
+	}
+	img.UnpremultiplyValue()
+	for x, a := range alphas {
+		exp := 0.8
+		if a == 0.0 {
+			exp = 0.0
+		}
+		c := img.NHSVAF64At(x, 0)
+		if math.Abs(c.V-exp) > 1e-12 || c.H != 50.0 || c.S != 0.5 || c.A != a {
+			t.Fatalf("Expected value %.5f but saw %v at (%d, 0)", exp, c, x)
+		}
+	}
+
+	// Premultiplying a sub-image should affect only that region of the
+	// shared pixels.
+	sub := img.SubImage(image.Rect(2, 0, 4, 1)).(*NHSVAF64)
+	sub.PremultiplyValue()
+	for x, a := range alphas {
+		exp := 0.8
+		switch {
+		case a == 0.0:
+			exp = 0.0
+		case x == 2 || x == 3:
+			exp *= a
+		}
+		if v := img.NHSVAF64At(x, 0).V; math.Abs(v-exp) > 1e-12 {
+			t.Fatalf("Expected value %.5f but saw %.5f at (%d, 0)", exp, v, x)
+		}
+	}
+	sub.UnpremultiplyValue()
+	for x := 2; x < 4; x++ {
+		if v := img.NHSVAF64At(x, 0).V; math.Abs(v-0.8) > 1e-12 {
+			t.Fatalf("Expected value 0.8 but saw %.5f at (%d, 0)", v, x)
+		}
+	}
+}
+
+// TestNormalizeValue confirms that NormalizeValue stretches values spanning
+// [0.2, 0.6] to span [0, 1] and leaves uniform images alone.
+func TestNormalizeValue(t *testing.T) {
+	img := NewNHSVAF64(image.Rect(0, 0, 5, 1))
+	for x := 0; x < 5; x++ {
+		v := 0.2 + 0.1*float64(x)
+		img.SetNHSVAF64(x, 0, hsvcolor.NHSVAF64{H: 200.0, S: 0.4, V: v, A: 1.0})
+	}
+	img.NormalizeValue()
+	for x := 0; x < 5; x++ {
+		c := img.NHSVAF64At(x, 0)
+		v := 0.25 * float64(x)
+		if math.Abs(c.V-v) > 1e-12 || c.H != 200.0 || c.S != 0.4 || c.A != 1.0 {
+			t.Fatalf("Expected value %.5f but saw %v at (%d, 0)", v, c, x)
+		}
+	}
+
+	// A uniform image should be left unchanged.
+	flat := NewNHSVAF64(image.Rect(0, 0, 3, 3))
+	for i := 0; i < len(flat.Pix); i += 4 {
+		copy(flat.Pix[i:i+4], []float64{30.0, 1.0, 0.3, 1.0})
+	}
+	flat.NormalizeValue()
+	for i := 2; i < len(flat.Pix); i += 4 {
+		if flat.Pix[i] != 0.3 {
+			t.Fatalf("Expected a uniform value of 0.3 to be left alone but saw %.5f", flat.Pix[i])
+		}
+	}
+}
+
+// TestApplySepia confirms that ApplySepia assigns a common hue and saturation
+// while preserving value and alpha.
+func TestApplySepia(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: uint8(x * 16), S: uint8(y * 16), V: uint8(x*16 + y), A: uint8(255 - y)})
+		}
+	}
+	img.ApplySepia()
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			exp := hsvcolor.NHSVA{H: sepiaHue, S: sepiaSaturation, V: uint8(x*16 + y), A: uint8(255 - y)}
+			if c := img.NHSVAAt(x, y); c != exp {
+				t.Fatalf("Expected %v but saw %v at (%d, %d)", exp, c, x, y)
+			}
+		}
+	}
+}
+
+// TestChromaKey confirms that ChromaKey removes only sufficiently saturated
+// pixels near the target hue.
+func TestChromaKey(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 8, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 8; x++ {
+			c := hsvcolor.NHSVA{H: 0, S: 220, V: 200, A: 255} // Red
+			if x >= 4 {
+				c.H = uint8(80 + y*3) // Greens
+			}
+			img.SetNHSVA(x, y, c)
+		}
+	}
+	img.SetNHSVA(7, 3, hsvcolor.NHSVA{H: 85, S: 30, V: 200, A: 255}) // Grayish green
+	if !img.Opaque() {
+		t.Fatal("Expected the image to be opaque before keying")
+	}
+	img.ChromaKey(85, 10, 80)
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 8; x++ {
+			want := uint8(255)
+			if x >= 4 && !(x == 7 && y == 3) {
+				want = 0
+			}
+			if a := img.NHSVAAt(x, y).A; a != want {
+				t.Fatalf("Expected alpha %d but saw %d at (%d, %d)", want, a, x, y)
+			}
+		}
+	}
+	if img.Opaque() {
+		t.Fatal("Expected the image not to be opaque after keying")
+	}
+
+	// Tolerance should wrap around red.
+	// Hue 255 is the same red as hue 0.
+	reds := NewNHSVA(image.Rect(0, 0, 6, 1))
+	for x, h := range []uint8{250, 5, 30, 247, 246, 255} {
+		reds.SetNHSVA(x, 0, hsvcolor.NHSVA{H: h, S: 255, V: 255, A: 255})
+	}
+	reds.ChromaKey(0, 8, 0)
+	for x, want := range []uint8{0, 0, 255, 0, 255, 0} {
+		if a := reds.NHSVAAt(x, 0).A; a != want {
+			t.Fatalf("Expected alpha %d but saw %d at (%d, 0)", want, a, x)
+		}
+	}
+}
+
+// TestMultiplyAlpha confirms that MultiplyAlpha fades only alpha and only
+// within a sub-image.
+func TestMultiplyAlpha(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 8, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 8; x++ {
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: uint8(x * 30), S: 200, V: uint8(y * 100), A: uint8(x*32 + 31)})
+		}
+	}
+	img.SubImage(image.Rect(0, 0, 8, 1)).(*NHSVA).MultiplyAlpha(0.5)
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 8; x++ {
+			want := hsvcolor.NHSVA{H: uint8(x * 30), S: 200, V: uint8(y * 100), A: uint8(x*32 + 31)}
+			if y == 0 {
+				want.A = uint8(x*16 + 16) // Halves round up.
+			}
+			if c := img.NHSVAAt(x, y); c != want {
+				t.Fatalf("Expected %v but saw %v at (%d, %d)", want, c, x, y)
+			}
+		}
+	}
+
+	// Large factors should clamp.
+	img.MultiplyAlpha(10.0)
+	if a := img.NHSVAAt(3, 1).A; a != 255 {
+		t.Fatalf("Expected alpha 255 but saw %d", a)
+	}
+}
+
+// TestSetGlobalAlpha confirms that SetGlobalAlpha sets only alpha and that
+// Opaque reflects the change.
+func TestSetGlobalAlpha(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 5, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 5; x++ {
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: uint8(x * 40), S: uint8(y * 80), V: 150, A: uint8(x * 50)})
+		}
+	}
+	if img.Opaque() {
+		t.Fatal("Expected the image not to be opaque before setting alpha")
+	}
+	img.SetGlobalAlpha(255)
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 5; x++ {
+			want := hsvcolor.NHSVA{H: uint8(x * 40), S: uint8(y * 80), V: 150, A: 255}
+			if c := img.NHSVAAt(x, y); c != want {
+				t.Fatalf("Expected %v but saw %v at (%d, %d)", want, c, x, y)
+			}
+		}
+	}
+	if !img.Opaque() {
+		t.Fatal("Expected the image to be opaque after setting alpha")
+	}
+	img.MultiplyAlpha(0.5)
+	if img.Opaque() {
+		t.Fatal("Expected the image not to be opaque after fading")
+	}
+}
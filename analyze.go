@@ -0,0 +1,687 @@
+package hsvimage
+
+import (
+	"fmt"
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+	"math"
+	"sort"
+)
+
+// weightedHueHistogram bins an NHSVA image's hues into 256 bins, weighting
+// each pixel by its saturation times its value (each scaled to [0, 1]) so that
+// near-gray pixels contribute little.  It also returns the number of
+// non-transparent pixels encountered.  Fully transparent pixels are skipped.
+func (p *NHSVA) weightedHueHistogram() (hist [256]float64, n int) {
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			s := p.Pix[i : i+4 : i+4]
+			if s[3] == 0 {
+				continue
+			}
+			n++
+			hist[s[0]] += float64(s[1]) * float64(s[2]) / (255.0 * 255.0)
+		}
+	}
+	return hist, n
+}
+
+// A HarmonyScheme is one of the classic color-harmony schemes.
+type HarmonyScheme int
+
+// These are the harmony schemes recognized by DetectHarmony.
+const (
+	HarmonyNone               HarmonyScheme = iota // No discernible hues (e.g., grayscale)
+	HarmonyMonochromatic                           // A single hue
+	HarmonyAnalogous                               // Neighboring hues
+	HarmonyComplementary                           // Two opposite hues
+	HarmonySplitComplementary                      // A hue plus the two neighbors of its complement
+	HarmonyTriadic                                 // Three evenly spaced hues
+	HarmonyTetradic                                // Four evenly spaced hues
+)
+
+// String returns the name of a harmony scheme.
+func (hs HarmonyScheme) String() string {
+	switch hs {
+	case HarmonyNone:
+		return "none"
+	case HarmonyMonochromatic:
+		return "monochromatic"
+	case HarmonyAnalogous:
+		return "analogous"
+	case HarmonyComplementary:
+		return "complementary"
+	case HarmonySplitComplementary:
+		return "split complementary"
+	case HarmonyTriadic:
+		return "triadic"
+	case HarmonyTetradic:
+		return "tetradic"
+	default:
+		return "unknown"
+	}
+}
+
+// A harmonyTemplate describes a harmony scheme as a set of hue sectors.  Each
+// sector is centered on an offset (in degrees) from a base hue and extends
+// halfWidth degrees in each direction.
+type harmonyTemplate struct {
+	scheme    HarmonyScheme
+	offsets   []float64
+	halfWidth float64
+}
+
+// harmonyTemplates lists the templates considered by DetectHarmony, from
+// simplest to most complex.
+var harmonyTemplates = []harmonyTemplate{
+	{HarmonyMonochromatic, []float64{0.0}, 15.0},
+	{HarmonyAnalogous, []float64{0.0}, 45.0},
+	{HarmonyComplementary, []float64{0.0, 180.0}, 15.0},
+	{HarmonySplitComplementary, []float64{0.0, 150.0, 210.0}, 15.0},
+	{HarmonyTriadic, []float64{0.0, 120.0, 240.0}, 15.0},
+	{HarmonyTetradic, []float64{0.0, 90.0, 180.0, 270.0}, 15.0},
+}
+
+// harmonyTolerance is the amount by which a more complex template's fit must
+// exceed a simpler template's fit to be preferred.  Without it, the more
+// complex templates, which cover more of the hue circle, would always win.
+const harmonyTolerance = 0.05
+
+// DetectHarmony analyzes an image's hue distribution and reports which classic
+// color-harmony scheme best fits it, along with a confidence score in [0, 1].
+// The confidence is the fraction of the saturation- and value-weighted hue
+// mass that lies within the scheme's hue sectors at the best rotation.  An
+// image with no saturated, visible pixels is reported as HarmonyNone with zero
+// confidence.
+func (p *NHSVA) DetectHarmony() (HarmonyScheme, float64) {
+	// Acquire a weighted hue histogram.
+	hist, _ := p.weightedHueHistogram()
+	var total float64
+	for _, w := range hist {
+		total += w
+	}
+	if total == 0.0 {
+		return HarmonyNone, 0.0
+	}
+
+	// Fit each template at each rotation and retain the best fit.
+	best, bestFit := HarmonyNone, 0.0
+	for _, tmpl := range harmonyTemplates {
+		var fit float64
+		for rot := 0; rot < 256; rot++ {
+			base := hueDegrees(uint8(rot))
+			var covered float64
+			for h, w := range hist {
+				if w == 0.0 {
+					continue
+				}
+				hd := hueDegrees(uint8(h))
+				for _, ofs := range tmpl.offsets {
+					if hueDistance(hd, base+ofs) <= tmpl.halfWidth {
+						covered += w
+						break
+					}
+				}
+			}
+			fit = math.Max(fit, covered/total)
+		}
+		if best == HarmonyNone || fit > bestFit+harmonyTolerance {
+			best, bestFit = tmpl.scheme, fit
+		}
+	}
+	return best, bestFit
+}
+
+// dominantHueBins is the number of equal-width hue bins considered by
+// DominantHue.
+const dominantHueBins = 16
+
+// DominantHue returns the most common hue in an image, quantized to the center
+// of one of 16 equal divisions of the color wheel, and the fraction of
+// chromatic, non-transparent pixels whose hue falls in that division.  The
+// divisions are centered on red, so hues on either side of red share a bin.
+// Each pixel's vote is weighted by its saturation and value so that near-gray
+// pixels do not dominate.  Pixels with zero saturation, value, or alpha are
+// ignored.  DominantHue returns (0, 0) if no pixel has a nonzero weight.
+func (p *NHSVA) DominantHue() (uint8, float64) {
+	const binWidth = 360.0 / dominantHueBins // Degrees per bin
+	var weights [dominantHueBins]float64
+	var counts [dominantHueBins]int
+	n := 0
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			s := p.Pix[i : i+4 : i+4]
+			if s[1] == 0 || s[2] == 0 || s[3] == 0 {
+				continue
+			}
+			n++
+			b := int(hueDegrees(s[0])/binWidth+0.5) % dominantHueBins
+			weights[b] += float64(s[1]) * float64(s[2])
+			counts[b]++
+		}
+	}
+	best := 0
+	for b, w := range weights {
+		if w > weights[best] {
+			best = b
+		}
+	}
+	if weights[best] == 0.0 {
+		return 0, 0.0
+	}
+	hue := uint8(math.Round(float64(best) * 255.0 / dominantHueBins))
+	return hue, float64(counts[best]) / float64(n)
+}
+
+// HueInvariantHash computes a 64-bit perceptual hash of an image that is
+// unaffected by a uniform rotation of all hues.  The hash comprises four
+// 16-bit fields:
+//
+//   - the value structure: whether the mean value in each cell of a 4×4 grid
+//     exceeds the image's mean value;
+//   - the saturation structure, computed likewise;
+//   - the relative hue structure: whether each cell's saturation-weighted mean
+//     hue, measured relative to the image's most heavily weighted hue, lies in
+//     the first half of the color wheel; and
+//   - the relative hue distribution: whether each of 16 bins of relative hue
+//     holds more than its share of the saturation-weighted pixels.
+//
+// Hues are treated as lying on a 255-step wheel, on which 255 and 0 both
+// represent red.  Because hues are measured relative to a reference hue,
+// rotating every hue by the same number of steps leaves the hash unchanged.
+// If several hues tie for the greatest weight, the hash is computed relative
+// to each, and the smallest result is returned, so the choice of reference
+// does not depend on the hues' absolute positions.
+func (p *NHSVA) HueInvariantHash() uint64 {
+	const grid = 4
+	w, h := p.Rect.Dx(), p.Rect.Dy()
+	if w <= 0 || h <= 0 {
+		return 0
+	}
+
+	// Accumulate per-cell statistics, including a saturation-weighted
+	// histogram of each cell's hues.
+	var vSum, sSum [grid * grid]float64
+	var cellHist [grid * grid][255]float64
+	var cnt [grid * grid]int
+	var vTotal, sTotal float64
+	for y := 0; y < h; y++ {
+		cy := y * grid / h
+		i := p.PixOffset(p.Rect.Min.X, p.Rect.Min.Y+y)
+		for x := 0; x < w; x, i = x+1, i+4 {
+			s := p.Pix[i : i+4 : i+4]
+			cell := cy*grid + x*grid/w
+			sf, vf := float64(s[1]), float64(s[2])
+			vSum[cell] += vf
+			sSum[cell] += sf
+			cellHist[cell][s[0]%255] += sf
+			cnt[cell]++
+			vTotal += vf
+			sTotal += sf
+		}
+	}
+
+	// Compute the value and saturation structure, which do not depend on
+	// hue.
+	var base uint64
+	n := float64(w * h)
+	for c := 0; c < grid*grid; c++ {
+		if cnt[c] == 0 {
+			continue
+		}
+		cn := float64(cnt[c])
+		if vSum[c]/cn > vTotal/n {
+			base |= 1 << uint(c)
+		}
+		if sSum[c]/cn > sTotal/n {
+			base |= 1 << uint(16+c)
+		}
+	}
+
+	// Find the candidate reference hues: those with the greatest weight.
+	// If no pixel is saturated, the hue fields are empty regardless of the
+	// reference.
+	var hist [255]float64
+	for c := range cellHist {
+		for hb, wt := range cellHist[c] {
+			hist[hb] += wt
+		}
+	}
+	var refs []int
+	maxWt := 0.0
+	for hb, wt := range hist {
+		switch {
+		case wt > maxWt:
+			refs, maxWt = append(refs[:0], hb), wt
+		case wt == maxWt && wt > 0.0:
+			refs = append(refs, hb)
+		}
+	}
+	if len(refs) == 0 {
+		return base
+	}
+
+	// Compute the hue fields relative to each candidate and keep the
+	// smallest hash.  Relative hues are visited in the same order for every
+	// candidate so that rotated images produce bit-identical sums.
+	best := ^uint64(0)
+	for _, ref := range refs {
+		hash := base
+		var relHist [16]float64
+		for c := 0; c < grid*grid; c++ {
+			var hs hueSum
+			for rel := 0; rel < 255; rel++ {
+				wt := cellHist[c][(ref+rel)%255]
+				hs.add(hueDegrees(uint8(rel)), wt)
+				relHist[rel*16/255] += wt
+			}
+			if m := hs.mean(); m > 0.0 && m < 180.0 {
+				hash |= 1 << uint(32+c)
+			}
+		}
+		for b, wt := range relHist {
+			if wt > sTotal/16.0 {
+				hash |= 1 << uint(48+b)
+			}
+		}
+		if hash < best {
+			best = hash
+		}
+	}
+	return best
+}
+
+// Histogram counts the occurrences of each 8-bit value in each of an image's
+// hue, saturation, value, and alpha channels.  Only pixels within the image's
+// bounds are counted.
+func (p *NHSVA) Histogram() (h, s, v, a [256]uint32) {
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			px := p.Pix[i : i+4 : i+4]
+			h[px[0]]++
+			s[px[1]]++
+			v[px[2]]++
+			a[px[3]]++
+		}
+	}
+	return h, s, v, a
+}
+
+// BlockinessScore estimates the severity of JPEG-like block artifacts in an
+// image's value channel.  It compares the mean absolute value difference
+// between horizontally or vertically adjacent pixels that straddle a block
+// boundary (every blockSize pixels from the image's minimum corner) with the
+// mean absolute difference between adjacent pixels within a block.  A score
+// near 1 indicates no blockiness; larger scores indicate stronger
+// discontinuities at block boundaries.  BlockinessScore returns 0 if blockSize
+// is less than 2 or the image is too small to contain a block boundary.
+func (p *NHSVA) BlockinessScore(blockSize int) float64 {
+	if blockSize < 2 {
+		return 0.0
+	}
+	var edgeSum, innerSum float64
+	var edgeN, innerN int
+	tally := func(v0, v1 uint8, seam bool) {
+		d := math.Abs(float64(v0) - float64(v1))
+		if seam {
+			edgeSum += d
+			edgeN++
+		} else {
+			innerSum += d
+			innerN++
+		}
+	}
+	w, h := p.Rect.Dx(), p.Rect.Dy()
+	for y := 0; y < h; y++ {
+		i := p.PixOffset(p.Rect.Min.X, p.Rect.Min.Y+y)
+		for x := 0; x < w; x, i = x+1, i+4 {
+			v := p.Pix[i+2]
+			if x+1 < w {
+				tally(v, p.Pix[i+4+2], (x+1)%blockSize == 0)
+			}
+			if y+1 < h {
+				tally(v, p.Pix[i+p.Stride+2], (y+1)%blockSize == 0)
+			}
+		}
+	}
+	if edgeN == 0 || innerN == 0 {
+		return 0.0
+	}
+	return (edgeSum/float64(edgeN) + 1.0) / (innerSum/float64(innerN) + 1.0)
+}
+
+// IsGrayscale reports whether every non-transparent pixel in an image has zero
+// saturation.  An empty image is considered grayscale.
+func (p *NHSVA) IsGrayscale() bool {
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			if p.Pix[i+1] != 0 && p.Pix[i+3] != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// NonTransparentBounds returns the smallest rectangle that contains every
+// pixel whose alpha is nonzero, expressed in the image's own coordinates.  It
+// returns the empty rectangle if the image is fully transparent.
+func (p *NHSVA) NonTransparentBounds() image.Rectangle {
+	var b image.Rectangle
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			if p.Pix[i+3] == 0 {
+				continue
+			}
+			b = b.Union(image.Rect(x, y, x+1, y+1))
+		}
+	}
+	return b
+}
+
+// Stats returns the arithmetic means of the saturation, value, and alpha
+// channels over every pixel within the image's bounds.  All pixels, including
+// fully transparent ones, contribute equally; the means are not weighted by
+// alpha.  An empty image yields all zeros.
+func (p *NHSVAF64) Stats() (meanS, meanV, meanA float64) {
+	if p.Rect.Empty() {
+		return 0.0, 0.0, 0.0
+	}
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			s := p.Pix[i : i+4 : i+4]
+			meanS += s[1]
+			meanV += s[2]
+			meanA += s[3]
+		}
+	}
+	nf := float64(p.Rect.Dx() * p.Rect.Dy())
+	return meanS / nf, meanV / nf, meanA / nf
+}
+
+// RegionMean returns the average color of the pixels within r, clipped to
+// the image's bounds.  Value and alpha are averaged linearly.  Hue and
+// saturation are averaged as vectors on the color wheel, with each pixel
+// contributing a vector whose angle is its hue and whose length is its
+// saturation.  The mean vector's angle is the resulting hue, and its length is
+// the resulting saturation.  Hence, pixels that agree in hue average to their
+// arithmetic-mean saturation, while opposing hues cancel, yielding a
+// desaturated (and, if they cancel completely, red-hued gray) result.  An
+// empty region yields the zero color.
+func (p *NHSVAF64) RegionMean(r image.Rectangle) hsvcolor.NHSVAF64 {
+	r = r.Intersect(p.Rect)
+	if r.Empty() {
+		return hsvcolor.NHSVAF64{}
+	}
+	var hs hueSum
+	var vSum, aSum float64
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := p.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x, i = x+1, i+4 {
+			s := p.Pix[i : i+4 : i+4]
+			hs.add(s[0], s[1])
+			vSum += s[2]
+			aSum += s[3]
+		}
+	}
+	n := float64(r.Dx() * r.Dy())
+	c := hsvcolor.NHSVAF64{
+		H: hs.mean(),
+		S: math.Hypot(hs.x, hs.y) / n,
+		V: vSum / n,
+		A: aSum / n,
+	}
+	if c.S < 1e-12 {
+		c.H, c.S = 0.0, 0.0 // Treat rounding residue as gray.
+	}
+	return c
+}
+
+// DiffImage visualizes the differences between two images with identical
+// bounds.  Each pixel of the resulting grayscale image is proportional to the
+// magnitude of the difference between the corresponding pixels of a and b,
+// treated as a vector of hue, saturation, value, and alpha differences, each
+// normalized to [0, 1].  As in hsvcolor.NearestNHSVA, hue is measured around
+// the color wheel and weighted by the lesser of the two saturations, so hue
+// differences between grays do not count.  The result is scaled so that
+// identical pixels map to 0 and maximally different pixels map to 255.
+// DiffImage returns an error if the two images' bounds differ.
+func DiffImage(a, b *NHSVA) (*image.Gray, error) {
+	if !a.Rect.Eq(b.Rect) {
+		return nil, fmt.Errorf("hsvimage: cannot compare images with bounds %v and %v", a.Rect, b.Rect)
+	}
+	r := a.Rect
+	dst := image.NewGray(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		ia, ib := a.PixOffset(r.Min.X, y), b.PixOffset(r.Min.X, y)
+		j := dst.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x, ia, ib, j = x+1, ia+4, ib+4, j+1 {
+			pa := a.Pix[ia : ia+4 : ia+4]
+			pb := b.Pix[ib : ib+4 : ib+4]
+			ca := hsvcolor.NHSVA{H: pa[0], S: pa[1], V: pa[2], A: pa[3]}
+			cb := hsvcolor.NHSVA{H: pb[0], S: pb[1], V: pb[2], A: pb[3]}
+			sMin := math.Min(float64(ca.S), float64(cb.S)) / 255.0
+			dh := sMin * ca.HueDistance(cb) / 180.0
+			ds := (float64(ca.S) - float64(cb.S)) / 255.0
+			dv := (float64(ca.V) - float64(cb.V)) / 255.0
+			da := (float64(ca.A) - float64(cb.A)) / 255.0
+			mag := math.Sqrt((dh*dh + ds*ds + dv*dv + da*da) / 3.0) // dh² + ds² <= 1
+			dst.Pix[j] = clampUint8(mag * 255.0)
+		}
+	}
+	return dst, nil
+}
+
+// ThresholdValue produces a binary mask with the same bounds as an image.
+// Each mask pixel is 255 where the corresponding image pixel's value is at
+// least t and 0 elsewhere.
+func (p *NHSVA) ThresholdValue(t uint8) *image.Gray {
+	mask := image.NewGray(p.Rect)
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		j := mask.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i, j = x+1, i+4, j+1 {
+			if p.Pix[i+2] >= t {
+				mask.Pix[j] = 255
+			}
+		}
+	}
+	return mask
+}
+
+// hueMaskMinSaturation is the saturation a pixel must exceed to be selected
+// by HueMask.  Hue is meaningless for grays and noisy for near-grays.
+const hueMaskMinSaturation = 16
+
+// HueMask produces an alpha mask with the same bounds as an image.  Each mask
+// pixel is 255 where the corresponding image pixel is reasonably saturated and
+// its hue lies within [lo, hi] and 0 elsewhere.  If lo > hi, the range wraps
+// around the color wheel, so, for example, HueMask(250, 10) selects reds.
+func (p *NHSVA) HueMask(lo, hi uint8) *image.Alpha {
+	mask := image.NewAlpha(p.Rect)
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		j := mask.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i, j = x+1, i+4, j+1 {
+			s := p.Pix[i : i+4 : i+4]
+			if s[1] <= hueMaskMinSaturation {
+				continue
+			}
+			if hueInRange(s[0], lo, hi) {
+				mask.Pix[j] = 255
+			}
+		}
+	}
+	return mask
+}
+
+// HueGradient computes the gradient of an image's hue field using central
+// differences (one-sided at the image's edges).  Differences are measured
+// around the color wheel, so hues on either side of 0°/360° are considered
+// close.  HueGradient returns two images with the same bounds as the input.
+// The first encodes gradient magnitude, with 255 representing a change of
+// 180° or more per pixel.  The second encodes gradient direction, with
+// [0, 255] spanning angles from -π to π as measured by math.Atan2(dy, dx).
+func (p *NHSVA) HueGradient() (*image.Gray, *image.Gray) {
+	mag := image.NewGray(p.Rect)
+	dir := image.NewGray(p.Rect)
+	r := p.Rect
+	hueAt := func(x, y int) float64 {
+		return hueDegrees(p.Pix[p.PixOffset(x, y)])
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		y0, y1 := clampInt(y-1, r.Min.Y, r.Max.Y-1), clampInt(y+1, r.Min.Y, r.Max.Y-1)
+		for x := r.Min.X; x < r.Max.X; x++ {
+			x0, x1 := clampInt(x-1, r.Min.X, r.Max.X-1), clampInt(x+1, r.Min.X, r.Max.X-1)
+			var gx, gy float64
+			if x1 > x0 {
+				gx = hueDelta(hueAt(x0, y), hueAt(x1, y)) / float64(x1-x0)
+			}
+			if y1 > y0 {
+				gy = hueDelta(hueAt(x, y0), hueAt(x, y1)) / float64(y1-y0)
+			}
+			i := mag.PixOffset(x, y)
+			mag.Pix[i] = clampUint8(math.Hypot(gx, gy) * 255.0 / 180.0)
+			dir.Pix[i] = clampUint8((math.Atan2(gy, gx) + math.Pi) * 255.0 / (2.0 * math.Pi))
+		}
+	}
+	return mag, dir
+}
+
+// CountColors returns the number of distinct colors in an image.
+func (p *NHSVA) CountColors() int {
+	seen := make(map[uint32]struct{})
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			s := p.Pix[i : i+4 : i+4]
+			seen[packNHSVA(hsvcolor.NHSVA{H: s[0], S: s[1], V: s[2], A: s[3]})] = struct{}{}
+		}
+	}
+	return len(seen)
+}
+
+// CoveringColors returns the fewest distinct colors that together account for
+// at least the given fraction of an image's pixels.  Colors are returned in
+// order of decreasing frequency, with ties broken deterministically.
+func (p *NHSVA) CoveringColors(fraction float64) []hsvcolor.NHSVA {
+	// Tally the occurrences of each color.
+	counts := make(map[hsvcolor.NHSVA]int)
+	total := 0
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			s := p.Pix[i : i+4 : i+4]
+			counts[hsvcolor.NHSVA{H: s[0], S: s[1], V: s[2], A: s[3]}]++
+			total++
+		}
+	}
+
+	// Sort the colors by decreasing frequency.
+	colors := make([]hsvcolor.NHSVA, 0, len(counts))
+	for c := range counts {
+		colors = append(colors, c)
+	}
+	sort.Slice(colors, func(i, j int) bool {
+		ci, cj := colors[i], colors[j]
+		if counts[ci] != counts[cj] {
+			return counts[ci] > counts[cj]
+		}
+		return packNHSVA(ci) < packNHSVA(cj)
+	})
+
+	// Return as many colors as are needed to reach the target fraction.
+	need := fraction * float64(total)
+	covered := 0
+	for i, c := range colors {
+		if float64(covered) >= need {
+			return colors[:i]
+		}
+		covered += counts[c]
+	}
+	return colors
+}
+
+// packNHSVA packs an NHSVA color into a uint32.
+func packNHSVA(c hsvcolor.NHSVA) uint32 {
+	return uint32(c.H)<<24 | uint32(c.S)<<16 | uint32(c.V)<<8 | uint32(c.A)
+}
+
+// hueSimilarityBins is the number of hue bins used by HueSimilarity.
+const hueSimilarityBins = 36
+
+// normalizedHueHistogram returns a saturation-weighted hue histogram of an
+// image, normalized to sum to 1.  Each pixel's weight is split between the
+// two bins nearest its hue, wrapping around the color wheel, so that small
+// hue shifts produce small histogram changes.  The histogram is all zeros if
+// the image contains no saturated, visible pixels.
+func (p *NHSVA) normalizedHueHistogram() [hueSimilarityBins]float64 {
+	var hist [hueSimilarityBins]float64
+	var total float64
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			s := p.Pix[i : i+4 : i+4]
+			if s[3] == 0 || s[1] == 0 {
+				continue
+			}
+			w := float64(s[1]) / 255.0
+			pos := hueDegrees(s[0]) * hueSimilarityBins / 360.0
+			b0 := int(pos)
+			frac := pos - float64(b0)
+			hist[b0%hueSimilarityBins] += w * (1.0 - frac)
+			hist[(b0+1)%hueSimilarityBins] += w * frac
+			total += w
+		}
+	}
+	if total > 0.0 {
+		for b := range hist {
+			hist[b] /= total
+		}
+	}
+	return hist
+}
+
+// HueSimilarity compares the color schemes of two images, returning a value
+// in [0, 1], with 1 indicating identical hue distributions.  Both images are
+// converted to NHSVA, and their saturation-weighted hue histograms are
+// compared by histogram intersection.  Two images with no saturated, visible
+// pixels are considered identical; one such image is considered entirely
+// dissimilar to any image with color.
+func HueSimilarity(a, b image.Image) float64 {
+	ha := toNHSVA(a).normalizedHueHistogram()
+	hb := toNHSVA(b).normalizedHueHistogram()
+	var sim, sa, sb float64
+	for i := range ha {
+		sim += math.Min(ha[i], hb[i])
+		sa += ha[i]
+		sb += hb[i]
+	}
+	if sa == 0.0 && sb == 0.0 {
+		return 1.0
+	}
+	return math.Min(1.0, sim)
+}
+
+// ColorfulnessMask returns a mask with the same bounds as an image in which
+// each pixel's gray level is the product of the corresponding image pixel's
+// saturation and value, scaled to [0, 255].  Vivid pixels therefore produce
+// bright mask values, and gray or dark pixels produce dark mask values.  The
+// mask is suitable for driving spatially adaptive processing.
+func (p *NHSVA) ColorfulnessMask() *image.Gray {
+	mask := image.NewGray(p.Rect)
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		j := mask.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i, j = x+1, i+4, j+1 {
+			mask.Pix[j] = uint8((uint32(p.Pix[i+1])*uint32(p.Pix[i+2]) + 127) / 255)
+		}
+	}
+	return mask
+}
@@ -0,0 +1,659 @@
+// This file tests analyses of HSV images.
+
+package hsvimage
+
+import (
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// TestDetectHarmony confirms that an image composed of two complementary hues
+// is reported as complementary.
+func TestDetectHarmony(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			h := uint8(20)
+			if x >= 5 {
+				h = 148 // 20 + 128, or 180 degrees away
+			}
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: h, S: 255, V: 255, A: 255})
+		}
+	}
+	scheme, conf := img.DetectHarmony()
+	if scheme != HarmonyComplementary || conf < 0.9 {
+		t.Fatalf("Expected a complementary scheme with high confidence but saw %v with confidence %.3f", scheme, conf)
+	}
+
+	// A grayscale image should exhibit no harmony.
+	gray := NewNHSVA(image.Rect(0, 0, 4, 4))
+	scheme, conf = gray.DetectHarmony()
+	if scheme != HarmonyNone || conf != 0.0 {
+		t.Fatalf("Expected no harmony scheme but saw %v with confidence %.3f", scheme, conf)
+	}
+}
+
+// TestDominantHue confirms that the most heavily represented hue wins, that
+// reds on both sides of the wheel's seam share a bin, and that gray, black,
+// and transparent pixels are not counted.
+func TestDominantHue(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			var c hsvcolor.NHSVA
+			switch y {
+			case 0, 1:
+				c = hsvcolor.NHSVA{H: 0, S: 255, V: 255, A: 255} // Red
+			case 2:
+				c = hsvcolor.NHSVA{H: 255, S: 255, V: 255, A: 255} // Red
+			case 3:
+				c = hsvcolor.NHSVA{H: 250, S: 255, V: 255, A: 255} // Nearly red
+			case 4, 5, 6:
+				c = hsvcolor.NHSVA{H: 170, S: 255, V: 255, A: 255} // Blue
+			case 7:
+				c = hsvcolor.NHSVA{H: 0, S: 0, V: 128, A: 255} // Gray
+			case 8:
+				c = hsvcolor.NHSVA{H: 0, S: 255, V: 0, A: 255} // Black
+			default:
+				c = hsvcolor.NHSVA{H: 170, S: 255, V: 255, A: 0} // Transparent blue
+			}
+			img.SetNHSVA(x, y, c)
+		}
+	}
+	hue, frac := img.DominantHue()
+	if hue != 0 {
+		t.Fatalf("Expected a dominant hue of 0 but saw %d", hue)
+	}
+	if frac != 40.0/70.0 {
+		t.Fatalf("Expected a fraction of %.5f but saw %.5f", 40.0/70.0, frac)
+	}
+
+	// Without the reds, blue should dominate every counted pixel.
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 4; y++ {
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: 0, S: 0, V: 200, A: 255})
+		}
+	}
+	hue, frac = img.DominantHue()
+	if d := hueDistance(hueDegrees(hue), hueDegrees(170)); d > 360.0/dominantHueBins/2.0 {
+		t.Fatalf("Expected a dominant hue near 170 but saw %d", hue)
+	}
+	if frac != 1.0 {
+		t.Fatalf("Expected a fraction of 1 but saw %.5f", frac)
+	}
+}
+
+// TestHueInvariantHash confirms that rotating all hues leaves the hash
+// unchanged but that altering an image's structure changes it.
+func TestHueInvariantHash(t *testing.T) {
+	// Draw a structured image.
+	const wd, ht = 16, 16
+	img := NewNHSVA(image.Rect(0, 0, wd, ht))
+	for y := 0; y < ht; y++ {
+		for x := 0; x < wd; x++ {
+			c := hsvcolor.NHSVA{
+				H: uint8(200 + x*7 + y*3),
+				S: uint8(40 + y*12),
+				V: uint8(255 - x*15),
+				A: 255,
+			}
+			if x < 6 && y > 9 {
+				c.H += 60
+			}
+			img.SetNHSVA(x, y, c)
+		}
+	}
+
+	// Every rotation around the 255-step color wheel should preserve the
+	// hash.
+	h0 := img.HueInvariantHash()
+	rot := NewNHSVA(img.Rect)
+	for k := 1; k < 255; k++ {
+		for i := 0; i < len(img.Pix); i += 4 {
+			copy(rot.Pix[i:i+4], img.Pix[i:i+4])
+			rot.Pix[i] = uint8((int(img.Pix[i]) + k) % 255)
+		}
+		if h1 := rot.HueInvariantHash(); h1 != h0 {
+			t.Fatalf("Expected a rotation by %d to preserve the hash %016x but saw %016x", k, h0, h1)
+		}
+	}
+
+	// Draw a structurally different image.
+	diff := NewNHSVA(image.Rect(0, 0, wd, ht))
+	for y := 0; y < ht; y++ {
+		for x := 0; x < wd; x++ {
+			c := img.NHSVAAt(y, x)
+			c.V = uint8(y * 15)
+			diff.SetNHSVA(x, y, c)
+		}
+	}
+	if h2 := diff.HueInvariantHash(); h2 == h0 {
+		t.Fatalf("Expected a structurally different image to produce a different hash than %016x", h0)
+	}
+}
+
+// TestHueInvariantHashTies confirms that HueInvariantHash is unaffected by
+// hue rotation even when two hues tie for the greatest weight.
+func TestHueInvariantHashTies(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			c := hsvcolor.NHSVA{H: 20, S: 200, V: uint8(x * 30), A: 255}
+			if x >= 4 {
+				c.H = 100 // Equally weighted with hue 20
+			}
+			if y == 0 && x < 2 {
+				c.H, c.S = 150, 50
+			}
+			img.SetNHSVA(x, y, c)
+		}
+	}
+	img.SetNHSVA(7, 7, hsvcolor.NHSVA{H: 100, S: 200, V: 10, A: 255})
+	img.SetNHSVA(6, 7, hsvcolor.NHSVA{H: 20, S: 200, V: 10, A: 255})
+	h0 := img.HueInvariantHash()
+	rot := NewNHSVA(img.Rect)
+	for k := 1; k < 255; k++ {
+		for i := 0; i < len(img.Pix); i += 4 {
+			copy(rot.Pix[i:i+4], img.Pix[i:i+4])
+			rot.Pix[i] = uint8((int(img.Pix[i]) + k) % 255)
+		}
+		if h1 := rot.HueInvariantHash(); h1 != h0 {
+			t.Fatalf("Expected a rotation by %d to preserve the hash %016x but saw %016x", k, h0, h1)
+		}
+	}
+}
+
+// TestHistogram confirms that Histogram counts each channel of only the
+// pixels within an image's bounds.
+func TestHistogram(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: uint8(x), S: uint8(y), V: 200, A: 255})
+		}
+	}
+	sub := img.SubImage(image.Rect(2, 3, 6, 8)).(*NHSVA)
+	h, s, v, a := sub.Histogram()
+	var nh, ns, nv, na uint32
+	for i := 0; i < 256; i++ {
+		nh += h[i]
+		ns += s[i]
+		nv += v[i]
+		na += a[i]
+	}
+	if nh != 20 || ns != 20 || nv != 20 || na != 20 {
+		t.Fatalf("Expected each channel to total 20 but saw %d, %d, %d, %d", nh, ns, nv, na)
+	}
+	for x := 0; x < 10; x++ {
+		want := uint32(0)
+		if x >= 2 && x < 6 {
+			want = 5
+		}
+		if h[x] != want {
+			t.Fatalf("Expected %d pixels with hue %d but saw %d", want, x, h[x])
+		}
+	}
+	for y := 0; y < 10; y++ {
+		want := uint32(0)
+		if y >= 3 && y < 8 {
+			want = 4
+		}
+		if s[y] != want {
+			t.Fatalf("Expected %d pixels with saturation %d but saw %d", want, y, s[y])
+		}
+	}
+	if v[200] != 20 || a[255] != 20 {
+		t.Fatalf("Expected 20 pixels with value 200 and alpha 255 but saw %d and %d", v[200], a[255])
+	}
+}
+
+// TestBlockinessScore confirms that an image with value steps at 8-pixel
+// boundaries scores higher than a smooth image.
+func TestBlockinessScore(t *testing.T) {
+	smooth := NewNHSVA(image.Rect(0, 0, 32, 32))
+	blocky := NewNHSVA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			v := uint8(60 + x*2 + y*2)
+			smooth.SetNHSVA(x, y, hsvcolor.NHSVA{H: 30, S: 100, V: v, A: 255})
+			v = uint8(60 + (x/8)*16 + (y/8)*16 + (x % 3))
+			blocky.SetNHSVA(x, y, hsvcolor.NHSVA{H: 30, S: 100, V: v, A: 255})
+		}
+	}
+	ss, bs := smooth.BlockinessScore(8), blocky.BlockinessScore(8)
+	if bs <= 2.0*ss {
+		t.Fatalf("Expected the blocky image's score (%.3f) to greatly exceed the smooth image's (%.3f)", bs, ss)
+	}
+	if ss < 0.9 || ss > 1.1 {
+		t.Fatalf("Expected the smooth image's score to be about 1 but saw %.3f", ss)
+	}
+}
+
+// TestIsGrayscale confirms that IsGrayscale detects saturated, visible pixels.
+func TestIsGrayscale(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: uint8(x * 30), S: 0, V: uint8(y * 30), A: 255})
+		}
+	}
+	if !img.IsGrayscale() {
+		t.Fatal("Expected an all-gray image to be grayscale")
+	}
+	img.SetNHSVA(1, 1, hsvcolor.NHSVA{H: 0, S: 200, V: 200, A: 0})
+	if !img.IsGrayscale() {
+		t.Fatal("Expected a transparent colored pixel to be ignored")
+	}
+	img.SetNHSVA(6, 5, hsvcolor.NHSVA{H: 0, S: 1, V: 200, A: 255})
+	if img.IsGrayscale() {
+		t.Fatal("Expected a single colored pixel to make the image non-grayscale")
+	}
+	if sub := img.SubImage(image.Rect(0, 0, 6, 8)).(*NHSVA); !sub.IsGrayscale() {
+		t.Fatal("Expected a sub-image excluding the colored pixel to be grayscale")
+	}
+	if !NewNHSVA(image.Rectangle{}).IsGrayscale() {
+		t.Fatal("Expected an empty image to be grayscale")
+	}
+}
+
+// TestNonTransparentBounds confirms that NonTransparentBounds finds the
+// extent of non-transparent content.
+func TestNonTransparentBounds(t *testing.T) {
+	img := NewNHSVA(image.Rect(-10, 20, 30, 60))
+	if b := img.NonTransparentBounds(); !b.Empty() {
+		t.Fatalf("Expected an empty rectangle for a transparent image but saw %v", b)
+	}
+	for y := 35; y < 45; y++ {
+		for x := 5; x < 15; x++ {
+			if (x-10)*(x-10)+(y-40)*(y-40) < 25 {
+				img.SetNHSVA(x, y, hsvcolor.NHSVA{H: 30, S: 200, V: 200, A: 255})
+			}
+		}
+	}
+	img.SetNHSVA(9, 44, hsvcolor.NHSVA{H: 0, S: 0, V: 0, A: 1}) // Faint pixel
+	exp := image.Rect(6, 36, 15, 45)
+	if b := img.NonTransparentBounds(); !b.Eq(exp) {
+		t.Fatalf("Expected %v but saw %v", exp, b)
+	}
+	sub := img.SubImage(image.Rect(0, 0, 10, 40)).(*NHSVA)
+	exp = image.Rect(6, 36, 10, 40)
+	if b := sub.NonTransparentBounds(); !b.Eq(exp) {
+		t.Fatalf("Expected %v but saw %v for a sub-image", exp, b)
+	}
+}
+
+// TestStats confirms that Stats computes unweighted channel means.
+func TestStats(t *testing.T) {
+	img := NewNHSVAF64(image.Rect(0, 0, 4, 1))
+	img.SetNHSVAF64(0, 0, hsvcolor.NHSVAF64{H: 10.0, S: 0.2, V: 0.1, A: 1.0})
+	img.SetNHSVAF64(1, 0, hsvcolor.NHSVAF64{H: 20.0, S: 0.4, V: 0.3, A: 1.0})
+	img.SetNHSVAF64(2, 0, hsvcolor.NHSVAF64{H: 30.0, S: 0.6, V: 0.5, A: 0.5})
+	img.SetNHSVAF64(3, 0, hsvcolor.NHSVAF64{H: 40.0, S: 0.8, V: 0.7, A: 0.0})
+	s, v, a := img.Stats()
+	if math.Abs(s-0.5) > 1e-12 || math.Abs(v-0.4) > 1e-12 || math.Abs(a-0.625) > 1e-12 {
+		t.Fatalf("Expected means (0.5, 0.4, 0.625) but saw (%.5f, %.5f, %.5f)", s, v, a)
+	}
+	s, v, a = img.SubImage(image.Rect(1, 0, 3, 1)).(*NHSVAF64).Stats()
+	if math.Abs(s-0.5) > 1e-12 || math.Abs(v-0.4) > 1e-12 || math.Abs(a-0.75) > 1e-12 {
+		t.Fatalf("Expected means (0.5, 0.4, 0.75) but saw (%.5f, %.5f, %.5f)", s, v, a)
+	}
+	empty := NewNHSVAF64(image.Rect(0, 0, 0, 0))
+	if s, v, a = empty.Stats(); s != 0.0 || v != 0.0 || a != 0.0 {
+		t.Fatalf("Expected zeros for an empty image but saw (%.5f, %.5f, %.5f)", s, v, a)
+	}
+}
+
+// TestRegionMean confirms that RegionMean averages hues on the color wheel
+// and the other channels linearly.
+func TestRegionMean(t *testing.T) {
+	img := NewNHSVAF64(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			h := 0.0
+			if x%2 == 1 {
+				h = 180.0
+			}
+			if y >= 2 {
+				h = 350.0 + 20.0*float64(x%2) // 350 or 10
+			}
+			img.SetNHSVAF64(x, y, hsvcolor.NHSVAF64{H: h, S: 0.8, V: 0.25 * float64(x), A: 1.0 - 0.5*float64(y%2)})
+		}
+	}
+
+	// Opposite hues should cancel, leaving gray.
+	c := img.RegionMean(image.Rect(-5, -5, 4, 2))
+	if c.S > 1e-9 || c.H != 0.0 {
+		t.Fatalf("Expected opposite hues to cancel but saw %v", c)
+	}
+	if math.Abs(c.V-0.375) > 1e-12 || math.Abs(c.A-0.75) > 1e-12 {
+		t.Fatalf("Expected V=0.375 and A=0.75 but saw %v", c)
+	}
+
+	// Hues on either side of red should average to red.
+	c = img.RegionMean(image.Rect(0, 2, 2, 4))
+	if hueDistance(c.H, 0.0) > 1e-9 {
+		t.Fatalf("Expected hues 350 and 10 to average to 0 but saw %v", c)
+	}
+	if math.Abs(c.S-0.8*math.Cos(10.0*math.Pi/180.0)) > 1e-12 {
+		t.Fatalf("Expected slightly reduced saturation but saw %v", c)
+	}
+
+	// A single pixel should be its own mean.
+	c = img.RegionMean(image.Rect(3, 3, 4, 4))
+	if math.Abs(c.H-10.0) > 1e-9 || math.Abs(c.S-0.8) > 1e-12 || c.V != 0.75 || c.A != 0.5 {
+		t.Fatalf("Expected {10 0.8 0.75 0.5} but saw %v", c)
+	}
+	if c = img.RegionMean(image.Rect(10, 10, 20, 20)); c != (hsvcolor.NHSVAF64{}) {
+		t.Fatalf("Expected the zero color for an empty region but saw %v", c)
+	}
+}
+
+// TestDiffImage confirms that DiffImage highlights exactly the pixels that
+// differ.
+func TestDiffImage(t *testing.T) {
+	a := NewNHSVA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			a.SetNHSVA(x, y, hsvcolor.NHSVA{H: uint8(x * 20), S: 200, V: 150, A: 255})
+		}
+	}
+	b := NewNHSVA(a.Rect)
+	copy(b.Pix, a.Pix)
+	changed := image.Rect(2, 3, 5, 6)
+	for y := changed.Min.Y; y < changed.Max.Y; y++ {
+		for x := changed.Min.X; x < changed.Max.X; x++ {
+			c := b.NHSVAAt(x, y)
+			c.H += 128
+			b.SetNHSVA(x, y, c)
+		}
+	}
+	diff, err := DiffImage(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			g := diff.GrayAt(x, y).Y
+			if (image.Point{x, y}).In(changed) {
+				if g == 0 {
+					t.Fatalf("Expected a nonzero difference at (%d, %d)", x, y)
+				}
+			} else if g != 0 {
+				t.Fatalf("Expected no difference at (%d, %d) but saw %d", x, y, g)
+			}
+		}
+	}
+
+	// Transparent black versus opaque red should be maximally different.
+	black := NewNHSVA(image.Rect(0, 0, 1, 1))
+	red := NewNHSVA(image.Rect(0, 0, 1, 1))
+	red.SetNHSVA(0, 0, hsvcolor.NHSVA{H: 0, S: 255, V: 255, A: 255})
+	if diff, err = DiffImage(black, red); err != nil || diff.GrayAt(0, 0).Y != 255 {
+		t.Fatalf("Expected a maximal difference but saw %v (%v)", diff.GrayAt(0, 0).Y, err)
+	}
+	if _, err = DiffImage(a, black); err == nil {
+		t.Fatal("Expected an error when comparing images with different bounds")
+	}
+}
+
+// TestThresholdValue confirms that thresholding splits a gradient exactly at
+// the threshold.
+func TestThresholdValue(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 256, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 256; x++ {
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: 10, S: 20, V: uint8(x), A: 255})
+		}
+	}
+	sub := img.SubImage(image.Rect(50, 1, 200, 3)).(*NHSVA)
+	mask := sub.ThresholdValue(128)
+	if !mask.Rect.Eq(sub.Rect) {
+		t.Fatalf("Expected mask bounds %v but saw %v", sub.Rect, mask.Rect)
+	}
+	for y := 1; y < 3; y++ {
+		for x := 50; x < 200; x++ {
+			want := uint8(0)
+			if x >= 128 {
+				want = 255
+			}
+			if g := mask.GrayAt(x, y).Y; g != want {
+				t.Fatalf("Expected %d but saw %d at (%d, %d)", want, g, x, y)
+			}
+		}
+	}
+}
+
+// TestHueMask confirms that HueMask selects saturated pixels within a hue
+// range, both with and without wraparound.
+func TestHueMask(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 256, 2))
+	for x := 0; x < 256; x++ {
+		img.SetNHSVA(x, 0, hsvcolor.NHSVA{H: uint8(x), S: 200, V: 200, A: 255})
+		img.SetNHSVA(x, 1, hsvcolor.NHSVA{H: uint8(x), S: 5, V: 200, A: 255})
+	}
+	for _, rng := range [][2]uint8{{70, 100}, {250, 10}} {
+		lo, hi := rng[0], rng[1]
+		mask := img.HueMask(lo, hi)
+		if !mask.Rect.Eq(img.Rect) {
+			t.Fatalf("Expected mask bounds %v but saw %v", img.Rect, mask.Rect)
+		}
+		for x := 0; x < 256; x++ {
+			h := uint8(x)
+			want := uint8(0)
+			if (lo <= hi && h >= lo && h <= hi) || (lo > hi && (h >= lo || h <= hi)) {
+				want = 255
+			}
+			if a := mask.AlphaAt(x, 0).A; a != want {
+				t.Fatalf("[%d, %d]: Expected %d but saw %d at (%d, 0)", lo, hi, want, a, x)
+			}
+			if a := mask.AlphaAt(x, 1).A; a != 0 {
+				t.Fatalf("[%d, %d]: Expected a near-gray pixel to be excluded but saw %d at (%d, 1)", lo, hi, a, x)
+			}
+		}
+	}
+
+	// Spot-check the wraparound range.
+	mask := img.HueMask(250, 10)
+	for _, x := range []int{250, 255, 0, 10} {
+		if mask.AlphaAt(x, 0).A != 255 {
+			t.Fatalf("Expected hue %d to be selected", x)
+		}
+	}
+	for _, x := range []int{11, 128, 249} {
+		if mask.AlphaAt(x, 0).A != 0 {
+			t.Fatalf("Expected hue %d not to be selected", x)
+		}
+	}
+}
+
+// TestHueGradient confirms that a boundary between regions of equal value and
+// equal luminance but different hue produces a strong hue gradient, while
+// hues straddling 0° do not.
+func TestHueGradient(t *testing.T) {
+	// Draw two regions whose colors have the same luminance and value.
+	c0 := hsvcolor.NHSVA{H: 25, S: 255, V: 200, A: 255}
+	c1 := hsvcolor.NHSVA{H: 106, S: 255, V: 200, A: 255}
+	g0 := color.GrayModel.Convert(c0).(color.Gray)
+	g1 := color.GrayModel.Convert(c1).(color.Gray)
+	if g0 != g1 {
+		t.Fatalf("Test colors should have equal luminance but have %d and %d", g0.Y, g1.Y)
+	}
+	img := NewNHSVA(image.Rect(0, 0, 10, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 10; x++ {
+			if x < 5 {
+				img.SetNHSVA(x, y, c0)
+			} else {
+				img.SetNHSVA(x, y, c1)
+			}
+		}
+	}
+
+	// Ensure that the boundary produces a strong gradient that points in
+	// the +x direction.
+	mag, dir := img.HueGradient()
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 10; x++ {
+			m := mag.GrayAt(x, y).Y
+			switch x {
+			case 4, 5:
+				if m < 64 {
+					t.Fatalf("Expected a strong gradient at (%d, %d) but saw %d", x, y, m)
+				}
+				if d := dir.GrayAt(x, y).Y; d < 126 || d > 129 {
+					t.Fatalf("Expected a gradient direction of 0 radians at (%d, %d) but saw %d", x, y, d)
+				}
+			default:
+				if m != 0 {
+					t.Fatalf("Expected no gradient at (%d, %d) but saw %d", x, y, m)
+				}
+			}
+		}
+	}
+
+	// Ensure that hues on either side of 0° are considered close.
+	wrap := NewNHSVA(image.Rect(0, 0, 2, 1))
+	wrap.SetNHSVA(0, 0, hsvcolor.NHSVA{H: 253, S: 255, V: 255, A: 255})
+	wrap.SetNHSVA(1, 0, hsvcolor.NHSVA{H: 2, S: 255, V: 255, A: 255})
+	mag, _ = wrap.HueGradient()
+	if m := mag.GrayAt(0, 0).Y; m > 8 {
+		t.Fatalf("Expected a small gradient across 0 degrees but saw %d", m)
+	}
+}
+
+// TestCountColors confirms that CountColors counts distinct colors within an
+// image's bounds.
+func TestCountColors(t *testing.T) {
+	colors := []hsvcolor.NHSVA{
+		{H: 0, S: 255, V: 255, A: 255},
+		{H: 0, S: 255, V: 255, A: 254},
+		{H: 85, S: 255, V: 255, A: 255},
+		{H: 85, S: 254, V: 255, A: 255},
+		{H: 85, S: 255, V: 254, A: 255},
+	}
+	img := NewNHSVA(image.Rect(0, 0, 20, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			img.SetNHSVA(x, y, colors[(x+y)%len(colors)])
+		}
+	}
+	if n := img.CountColors(); n != 5 {
+		t.Fatalf("Expected 5 colors but saw %d", n)
+	}
+	if n := img.SubImage(image.Rect(3, 3, 5, 4)).(*NHSVA).CountColors(); n != 2 {
+		t.Fatalf("Expected 2 colors in a 2×1 sub-image but saw %d", n)
+	}
+	if n := NewNHSVA(image.Rectangle{}).CountColors(); n != 0 {
+		t.Fatalf("Expected 0 colors in an empty image but saw %d", n)
+	}
+}
+
+// TestCoveringColors confirms that CoveringColors returns the fewest, most
+// common colors needed to reach the requested coverage.
+func TestCoveringColors(t *testing.T) {
+	major := hsvcolor.NHSVA{H: 170, S: 255, V: 255, A: 255}
+	minor := []hsvcolor.NHSVA{
+		{H: 0, S: 255, V: 255, A: 255},
+		{H: 85, S: 255, V: 255, A: 255},
+	}
+	img := NewNHSVA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			c := major
+			if y == 0 {
+				c = minor[0]
+				if x >= 7 {
+					c = minor[1]
+				}
+			}
+			img.SetNHSVA(x, y, c)
+		}
+	}
+	if cs := img.CoveringColors(0.8); len(cs) != 1 || cs[0] != major {
+		t.Fatalf("Expected only %v to cover 80%% but saw %v", major, cs)
+	}
+	if cs := img.CoveringColors(0.9); len(cs) != 1 || cs[0] != major {
+		t.Fatalf("Expected only %v to cover 90%% but saw %v", major, cs)
+	}
+	if cs := img.CoveringColors(0.95); len(cs) != 2 || cs[1] != minor[0] {
+		t.Fatalf("Expected %v and %v to cover 95%% but saw %v", major, minor[0], cs)
+	}
+	if cs := img.CoveringColors(1.0); len(cs) != 3 || cs[2] != minor[1] {
+		t.Fatalf("Expected all three colors to cover 100%% but saw %v", cs)
+	}
+	if cs := img.CoveringColors(0.0); len(cs) != 0 {
+		t.Fatalf("Expected no colors to cover 0%% but saw %v", cs)
+	}
+}
+
+// TestHueSimilarity confirms that an image is maximally similar to itself and
+// less similar to an image with different hues.
+func TestHueSimilarity(t *testing.T) {
+	// Draw an image with a mix of reds and blues and an RGBA image with the
+	// same colors.
+	img := NewNHSVA(image.Rect(0, 0, 8, 8))
+	rgba := image.NewRGBA(img.Rect)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			c := hsvcolor.NHSVA{H: uint8(250 + x), S: 255, V: 255, A: 255}
+			if y >= 5 {
+				c = hsvcolor.NHSVA{H: 170, S: 200, V: 200, A: 255}
+			}
+			img.SetNHSVA(x, y, c)
+			rgba.Set(x, y, c)
+		}
+	}
+
+	// Draw an image that is mostly greens.
+	other := NewNHSVA(img.Rect)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			c := hsvcolor.NHSVA{H: 85, S: 255, V: 255, A: 255}
+			if y >= 7 {
+				c = hsvcolor.NHSVA{H: 170, S: 200, V: 200, A: 255}
+			}
+			other.SetNHSVA(x, y, c)
+		}
+	}
+
+	// Compare the images.
+	if s := HueSimilarity(img, img); math.Abs(s-1.0) > 1e-9 {
+		t.Fatalf("Expected an image to be maximally similar to itself but saw %.5f", s)
+	}
+	if s := HueSimilarity(img, rgba); s < 0.95 {
+		t.Fatalf("Expected an image to be highly similar to its RGBA equivalent but saw %.5f", s)
+	}
+	if s := HueSimilarity(img, other); s > 0.5 {
+		t.Fatalf("Expected differently hued images to be dissimilar but saw %.5f", s)
+	}
+}
+
+// TestColorfulnessMask confirms that vivid regions produce high mask values
+// and gray regions produce low mask values.
+func TestColorfulnessMask(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 8, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 8; x++ {
+			c := hsvcolor.NHSVA{H: 0, S: 0, V: 200, A: 255} // Flat gray
+			if x >= 4 {
+				c = hsvcolor.NHSVA{H: 60, S: 240, V: 250, A: 255} // Vivid
+			}
+			img.SetNHSVA(x, y, c)
+		}
+	}
+	mask := img.ColorfulnessMask()
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 8; x++ {
+			m := mask.GrayAt(x, y).Y
+			if x < 4 && m != 0 {
+				t.Fatalf("Expected a gray pixel to have a mask value of 0 but saw %d at (%d, %d)", m, x, y)
+			}
+			if x >= 4 && m != 235 {
+				t.Fatalf("Expected a vivid pixel to have a mask value of 235 but saw %d at (%d, %d)", m, x, y)
+			}
+		}
+	}
+}
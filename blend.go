@@ -0,0 +1,247 @@
+package hsvimage
+
+import (
+	"fmt"
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+	"image/color"
+	"math"
+)
+
+// DoubleExposure blends two images over their common bounds to simulate a
+// double exposure.  Value, saturation, and alpha are interpolated linearly by
+// t, with t=0 producing a and t=1 producing b.  Hue is interpolated along the
+// shorter arc of the color wheel, weighted by each pixel's saturation so that
+// a gray pixel does not pull a colorful pixel's hue toward red.
+func DoubleExposure(a, b *NHSVA, t float64) *NHSVA {
+	r := a.Rect.Intersect(b.Rect)
+	dst := NewNHSVA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		ia, ib, id := a.PixOffset(r.Min.X, y), b.PixOffset(r.Min.X, y), dst.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x, ia, ib, id = x+1, ia+4, ib+4, id+4 {
+			pa := a.Pix[ia : ia+4 : ia+4]
+			pb := b.Pix[ib : ib+4 : ib+4]
+			pd := dst.Pix[id : id+4 : id+4]
+			var hs hueSum
+			hs.add(hueDegrees(pa[0]), (1.0-t)*float64(pa[1]))
+			hs.add(hueDegrees(pb[0]), t*float64(pb[1]))
+			pd[0] = hueFromDegrees(hs.mean())
+			for c := 1; c < 4; c++ {
+				pd[c] = clampUint8((1.0-t)*float64(pa[c]) + t*float64(pb[c]))
+			}
+		}
+	}
+	return dst
+}
+
+// SplitCompare returns a side-by-side comparison of two images with identical
+// bounds.  Pixels left of column splitX are taken from before, pixels right of
+// column splitX are taken from after, and column splitX itself is drawn as an
+// opaque white divider.  SplitCompare returns an error if the two images'
+// bounds differ.
+func SplitCompare(before, after *NHSVA, splitX int) (*NHSVA, error) {
+	if !before.Rect.Eq(after.Rect) {
+		return nil, fmt.Errorf("hsvimage: cannot compare images with bounds %v and %v", before.Rect, after.Rect)
+	}
+	r := before.Rect
+	dst := NewNHSVA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		j := dst.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x, j = x+1, j+4 {
+			d := dst.Pix[j : j+4 : j+4]
+			switch {
+			case x < splitX:
+				i := before.PixOffset(x, y)
+				copy(d, before.Pix[i:i+4])
+			case x > splitX:
+				i := after.PixOffset(x, y)
+				copy(d, after.Pix[i:i+4])
+			default:
+				d[0], d[1], d[2], d[3] = 0, 0, 255, 255
+			}
+		}
+	}
+	return dst, nil
+}
+
+// overlay applies the overlay blend formula to a base and a blend value, both
+// in [0, 1].
+func overlay(a, b float64) float64 {
+	if a < 0.5 {
+		return 2.0 * a * b
+	}
+	return 1.0 - 2.0*(1.0-a)*(1.0-b)
+}
+
+// OverlaySaturation combines the saturation channels of two images over their
+// common bounds using the overlay blend mode.  The result takes its hue,
+// value, and alpha from base.
+func OverlaySaturation(base, blend *NHSVA) *NHSVA {
+	r := base.Rect.Intersect(blend.Rect)
+	dst := NewNHSVA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		ia, ib, id := base.PixOffset(r.Min.X, y), blend.PixOffset(r.Min.X, y), dst.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x, ia, ib, id = x+1, ia+4, ib+4, id+4 {
+			pd := dst.Pix[id : id+4 : id+4]
+			copy(pd, base.Pix[ia:ia+4])
+			s := overlay(float64(pd[1])/255.0, float64(blend.Pix[ib+1])/255.0)
+			pd[1] = clampUint8(s * 255.0)
+		}
+	}
+	return dst
+}
+
+// LightTrails composites a sequence of frames into a single image that
+// simulates a long exposure.  Each output pixel takes the color of whichever
+// frame's pixel has the greatest value at that location (the earliest such
+// frame in the case of ties), so bright moving objects leave trails.
+// LightTrails panics if frames is empty or if the frames' bounds differ.
+func LightTrails(frames []*NHSVA) *NHSVA {
+	if len(frames) == 0 {
+		panic("hsvimage: LightTrails requires at least one frame")
+	}
+	r := frames[0].Rect
+	for _, f := range frames[1:] {
+		if !f.Rect.Eq(r) {
+			panic("hsvimage: LightTrails requires frames with identical bounds")
+		}
+	}
+	dst := NewNHSVA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			best := frames[0]
+			bi := best.PixOffset(x, y)
+			for _, f := range frames[1:] {
+				fi := f.PixOffset(x, y)
+				if f.Pix[fi+2] > best.Pix[bi+2] {
+					best, bi = f, fi
+				}
+			}
+			j := dst.PixOffset(x, y)
+			copy(dst.Pix[j:j+4], best.Pix[bi:bi+4])
+		}
+	}
+	return dst
+}
+
+// Montage tiles a sequence of equally sized images into a grid with cols
+// columns, filling each row from left to right before moving to the next, to
+// form, e.g., a sprite sheet.  The result's bounds have their origin at
+// (0, 0).  Any grid cells left over after the last image are transparent.
+// Montage panics if imgs is empty, if cols is not positive, or if the images'
+// sizes differ.
+func Montage(imgs []*NHSVA, cols int) *NHSVA {
+	if len(imgs) == 0 {
+		panic("hsvimage: Montage requires at least one image")
+	}
+	if cols <= 0 {
+		panic("hsvimage: Montage requires a positive number of columns")
+	}
+	sz := imgs[0].Rect.Size()
+	for _, img := range imgs[1:] {
+		if img.Rect.Size() != sz {
+			panic("hsvimage: Montage requires images of identical size")
+		}
+	}
+	rows := (len(imgs) + cols - 1) / cols
+	dst := NewNHSVA(image.Rect(0, 0, cols*sz.X, rows*sz.Y))
+	n := 4 * sz.X
+	for k, img := range imgs {
+		x0, y0 := (k%cols)*sz.X, (k/cols)*sz.Y
+		for dy := 0; dy < sz.Y; dy++ {
+			i := img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y+dy)
+			j := dst.PixOffset(x0, y0+dy)
+			copy(dst.Pix[j:j+n], img.Pix[i:i+n])
+		}
+	}
+	return dst
+}
+
+// A BlendOp is a compositing operator used by CompositeNHSVAF64.
+type BlendOp int
+
+// These are the compositing operators supported by CompositeNHSVAF64.  All but
+// BlendAdd composite the blended color over the destination in the
+// Porter-Duff sense.
+const (
+	BlendOver     BlendOp = iota // Source over destination
+	BlendMultiply                // Product of source and destination
+	BlendScreen                  // Inverse of the product of the inverses
+	BlendAdd                     // Sum of source and destination, clamped
+)
+
+// String returns the name of a compositing operator.
+func (op BlendOp) String() string {
+	switch op {
+	case BlendOver:
+		return "over"
+	case BlendMultiply:
+		return "multiply"
+	case BlendScreen:
+		return "screen"
+	case BlendAdd:
+		return "add"
+	default:
+		return "unknown"
+	}
+}
+
+// CompositeNHSVAF64 composites the part of src starting at sp onto the part
+// of dst given by r using the given operator, in the manner of draw.Draw.
+// Because the operators are defined in terms of RGB, each pair of pixels is
+// converted to alpha-premultiplied RGB, blended, and converted back to HSV.
+// CompositeNHSVAF64 panics on an unknown operator.
+func CompositeNHSVAF64(dst, src *NHSVAF64, r image.Rectangle, sp image.Point, op BlendOp) {
+	if op < BlendOver || op > BlendAdd {
+		panic("hsvimage: unknown blend operator")
+	}
+
+	// Clip r to both images, adjusting sp to match.
+	orig := r.Min
+	r = r.Intersect(dst.Rect)
+	r = r.Intersect(src.Rect.Add(orig.Sub(sp)))
+	sp = sp.Add(r.Min.Sub(orig))
+
+	// Blend each pair of pixels.
+	const m = 65535.0
+	var out [4]float64 // Premultiplied R, G, B, A
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		sy := sp.Y + y - r.Min.Y
+		for x := r.Min.X; x < r.Max.X; x++ {
+			sx := sp.X + x - r.Min.X
+			sr, sg, sb, sa := src.NHSVAF64At(sx, sy).RGBA()
+			dr, dg, db, da := dst.NHSVAF64At(x, y).RGBA()
+			s := [4]float64{float64(sr) / m, float64(sg) / m, float64(sb) / m, float64(sa) / m}
+			d := [4]float64{float64(dr) / m, float64(dg) / m, float64(db) / m, float64(da) / m}
+			as, ad := s[3], d[3]
+			for c := 0; c < 3; c++ {
+				switch op {
+				case BlendOver:
+					out[c] = s[c] + d[c]*(1.0-as)
+				case BlendMultiply:
+					out[c] = s[c]*(1.0-ad) + d[c]*(1.0-as) + s[c]*d[c]
+				case BlendScreen:
+					out[c] = s[c] + d[c] - s[c]*d[c]
+				case BlendAdd:
+					out[c] = s[c] + d[c]
+				}
+			}
+			if op == BlendAdd {
+				out[3] = as + ad
+			} else {
+				out[3] = as + ad - as*ad
+			}
+			var q [4]uint16
+			for c := range out {
+				q[c] = uint16(math.Round(math.Max(0.0, math.Min(1.0, out[c])) * m))
+			}
+			for c := 0; c < 3; c++ {
+				if q[c] > q[3] {
+					q[c] = q[3] // Keep premultiplied colors valid.
+				}
+			}
+			rgba := color.RGBA64{R: q[0], G: q[1], B: q[2], A: q[3]}
+			dst.SetNHSVAF64(x, y, hsvcolor.NHSVAF64Model.Convert(rgba).(hsvcolor.NHSVAF64))
+		}
+	}
+}
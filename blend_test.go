@@ -0,0 +1,236 @@
+// This file tests combining multiple HSV images.
+
+package hsvimage
+
+import (
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+	"math"
+	"testing"
+)
+
+// TestDoubleExposure confirms that a 50% double exposure averages values and
+// blends hues along the shorter arc.
+func TestDoubleExposure(t *testing.T) {
+	a := NewNHSVA(image.Rect(0, 0, 4, 4))
+	b := NewNHSVA(image.Rect(2, 2, 6, 6))
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 6; x++ {
+			a.SetNHSVA(x, y, hsvcolor.NHSVA{H: 245, S: 200, V: 100, A: 255})
+			b.SetNHSVA(x, y, hsvcolor.NHSVA{H: 15, S: 200, V: 200, A: 255})
+		}
+	}
+	dx := DoubleExposure(a, b, 0.5)
+	if !image.Rect(2, 2, 4, 4).Eq(dx.Bounds()) {
+		t.Fatalf("Expected bounds %v but saw %v", image.Rect(2, 2, 4, 4), dx.Bounds())
+	}
+	for y := 2; y < 4; y++ {
+		for x := 2; x < 4; x++ {
+			c := dx.NHSVAAt(x, y)
+			if c.V != 150 || c.S != 200 || c.A != 255 {
+				t.Fatalf("Expected averaged values but saw %v at (%d, %d)", c, x, y)
+			}
+			if c.H > 3 && c.H < 253 {
+				t.Fatalf("Expected a hue near 0 but saw %v at (%d, %d)", c, x, y)
+			}
+		}
+	}
+}
+
+// TestSplitCompare confirms that pixels are taken from the correct image on
+// each side of the divider.
+func TestSplitCompare(t *testing.T) {
+	r := image.Rect(-3, 0, 7, 5)
+	before := NewNHSVA(r)
+	after := NewNHSVA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			before.SetNHSVA(x, y, hsvcolor.NHSVA{H: uint8(x + 10), S: 10, V: 100, A: 255})
+			after.SetNHSVA(x, y, hsvcolor.NHSVA{H: uint8(x + 10), S: 200, V: 50, A: 255})
+		}
+	}
+	split, err := SplitCompare(before, after, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			var want hsvcolor.NHSVA
+			switch {
+			case x < 2:
+				want = before.NHSVAAt(x, y)
+			case x > 2:
+				want = after.NHSVAAt(x, y)
+			default:
+				want = hsvcolor.NHSVA{H: 0, S: 0, V: 255, A: 255}
+			}
+			if c := split.NHSVAAt(x, y); c != want {
+				t.Fatalf("Expected %v but saw %v at (%d, %d)", want, c, x, y)
+			}
+		}
+	}
+	if _, err = SplitCompare(before, NewNHSVA(image.Rect(0, 0, 10, 5)), 2); err == nil {
+		t.Fatal("Expected images with different bounds to be rejected")
+	}
+}
+
+// TestOverlaySaturation confirms that the overlay formula is applied to the
+// saturation channel only.
+func TestOverlaySaturation(t *testing.T) {
+	type test struct {
+		Base, Blend, Result uint8
+	}
+	tests := []test{
+		{0, 200, 0},     // 2*0*b = 0
+		{64, 128, 64},   // 2 * 64/255 * 128/255 * 255 = 64.25
+		{100, 255, 200}, // 2 * 100/255 * 255/255 * 255 = 200
+		{200, 100, 188}, // (1 - 2 * 55/255 * 155/255) * 255 = 188.14
+		{255, 10, 255},  // 1 - 2*0*(1-b) = 1
+		{128, 0, 1},     // (1 - 2 * 127/255 * 1) * 255 = 1
+	}
+	base := NewNHSVA(image.Rect(0, 0, len(tests), 1))
+	blend := NewNHSVA(image.Rect(0, 0, len(tests), 1))
+	for x, tc := range tests {
+		base.SetNHSVA(x, 0, hsvcolor.NHSVA{H: 40, S: tc.Base, V: 90, A: 200})
+		blend.SetNHSVA(x, 0, hsvcolor.NHSVA{H: 140, S: tc.Blend, V: 190, A: 255})
+	}
+	ov := OverlaySaturation(base, blend)
+	for x, tc := range tests {
+		want := hsvcolor.NHSVA{H: 40, S: tc.Result, V: 90, A: 200}
+		if c := ov.NHSVAAt(x, 0); c != want {
+			t.Fatalf("Expected overlay(%d, %d) to produce %v but saw %v", tc.Base, tc.Blend, want, c)
+		}
+	}
+}
+
+// TestLightTrails confirms that a bright object moving across a dark scene
+// leaves a continuous trail.
+func TestLightTrails(t *testing.T) {
+	const wd, ht = 12, 5
+	dark := hsvcolor.NHSVA{H: 170, S: 100, V: 30, A: 255}
+	light := hsvcolor.NHSVA{H: 40, S: 200, V: 250, A: 255}
+	frames := make([]*NHSVA, wd)
+	for f := range frames {
+		frames[f] = NewNHSVA(image.Rect(0, 0, wd, ht))
+		for y := 0; y < ht; y++ {
+			for x := 0; x < wd; x++ {
+				c := dark
+				if x == f && y == 2 {
+					c = light
+				}
+				frames[f].SetNHSVA(x, y, c)
+			}
+		}
+	}
+	trails := LightTrails(frames)
+	for y := 0; y < ht; y++ {
+		for x := 0; x < wd; x++ {
+			want := dark
+			if y == 2 {
+				want = light
+			}
+			if c := trails.NHSVAAt(x, y); c != want {
+				t.Fatalf("Expected %v but saw %v at (%d, %d)", want, c, x, y)
+			}
+		}
+	}
+}
+
+// TestMontage confirms that Montage places each image in the expected grid
+// cell.
+func TestMontage(t *testing.T) {
+	imgs := make([]*NHSVA, 5)
+	for k := range imgs {
+		// Give each image different bounds but the same size.
+		img := NewNHSVA(image.Rect(k, -k, k+2, 2-k))
+		for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+			for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+				img.SetNHSVA(x, y, hsvcolor.NHSVA{H: uint8(k * 40), S: uint8(x - k), V: uint8(y + k), A: 255})
+			}
+		}
+		imgs[k] = img
+	}
+	m := Montage(imgs[:4], 2)
+	if !m.Rect.Eq(image.Rect(0, 0, 4, 4)) {
+		t.Fatalf("Expected bounds %v but saw %v", image.Rect(0, 0, 4, 4), m.Rect)
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			k := (y/2)*2 + x/2
+			exp := hsvcolor.NHSVA{H: uint8(k * 40), S: uint8(x % 2), V: uint8(y % 2), A: 255}
+			if c := m.NHSVAAt(x, y); c != exp {
+				t.Fatalf("Expected %v but saw %v at (%d, %d)", exp, c, x, y)
+			}
+		}
+	}
+
+	// A partial last row should be left transparent.
+	m = Montage(imgs, 2)
+	if !m.Rect.Eq(image.Rect(0, 0, 4, 6)) {
+		t.Fatalf("Expected bounds %v but saw %v", image.Rect(0, 0, 4, 6), m.Rect)
+	}
+	if c := m.NHSVAAt(0, 4); c.H != 160 || c.A != 255 {
+		t.Fatalf("Expected the fifth image at (0, 4) but saw %v", c)
+	}
+	if c := m.NHSVAAt(3, 5); c != (hsvcolor.NHSVA{}) {
+		t.Fatalf("Expected an empty cell to be transparent but saw %v", c)
+	}
+
+	// Mismatched sizes should panic.
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected Montage to panic on mismatched sizes")
+		}
+	}()
+	Montage([]*NHSVA{imgs[0], NewNHSVA(image.Rect(0, 0, 3, 2))}, 2)
+}
+
+// TestCompositeNHSVAF64 confirms that CompositeNHSVAF64 implements its
+// operators' identities and annihilators.
+func TestCompositeNHSVAF64(t *testing.T) {
+	c := hsvcolor.NHSVAF64{H: 200.0, S: 0.6, V: 0.8, A: 1.0}
+	white := hsvcolor.NHSVAF64{H: 0.0, S: 0.0, V: 1.0, A: 1.0}
+	black := hsvcolor.NHSVAF64{H: 0.0, S: 0.0, V: 0.0, A: 1.0}
+	closeTo := func(a, b hsvcolor.NHSVAF64) bool {
+		const eps = 1e-4
+		return hueDistance(a.H, b.H) < 0.01 && math.Abs(a.S-b.S) < eps &&
+			math.Abs(a.V-b.V) < eps && math.Abs(a.A-b.A) < eps
+	}
+	fill := func(img *NHSVAF64, c hsvcolor.NHSVAF64) {
+		r := img.Bounds()
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				img.SetNHSVAF64(x, y, c)
+			}
+		}
+	}
+	for _, tc := range []struct {
+		op       BlendOp
+		src, exp hsvcolor.NHSVAF64
+	}{
+		{BlendMultiply, white, c},
+		{BlendMultiply, black, black},
+		{BlendScreen, black, c},
+		{BlendScreen, white, white},
+		{BlendAdd, black, c},
+		{BlendOver, black, black},
+		{BlendOver, hsvcolor.NHSVAF64{H: 0.0, S: 0.0, V: 0.0, A: 0.0}, c},
+	} {
+		dst := NewNHSVAF64(image.Rect(0, 0, 4, 4))
+		fill(dst, c)
+		src := NewNHSVAF64(image.Rect(10, 10, 12, 12))
+		fill(src, tc.src)
+		CompositeNHSVAF64(dst, src, image.Rect(1, 1, 5, 5), image.Pt(10, 10), tc.op)
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 4; x++ {
+				exp := c
+				if x >= 1 && x < 3 && y >= 1 && y < 3 {
+					exp = tc.exp
+				}
+				if got := dst.NHSVAF64At(x, y); !closeTo(got, exp) {
+					t.Fatalf("%v: Expected %v but saw %v at (%d, %d)", tc.op, exp, got, x, y)
+				}
+			}
+		}
+	}
+}
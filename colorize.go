@@ -0,0 +1,76 @@
+package hsvimage
+
+import (
+	"github.com/spakin/hsvimage/hsvcolor"
+)
+
+// Tritone returns a copy of an image in which each pixel's color is determined
+// solely by its value, which is mapped through three color stops: shadow at a
+// value of 0, mid at a value of midPoint, and highlight at a value of 1.
+// midPoint is clamped to the open interval (0, 1).  Colors between stops are
+// interpolated with hsvcolor.LerpNHSVAF64, so hues follow the shorter arc of
+// the color wheel.  Each pixel retains its original alpha; the stops' alpha
+// channels are ignored.
+func (p *NHSVA) Tritone(shadow, mid, highlight hsvcolor.NHSVA, midPoint float64) *NHSVA {
+	// Keep midPoint strictly between 0 and 1 so that neither segment of the
+	// ramp has zero width.  The negated comparisons also catch NaN.
+	const eps = 1e-9
+	if !(midPoint >= eps) {
+		midPoint = eps
+	}
+	if !(midPoint <= 1.0-eps) {
+		midPoint = 1.0 - eps
+	}
+
+	// Precompute the color for each possible value.
+	sf, mf, hf := shadow.ToF64(), mid.ToF64(), highlight.ToF64()
+	var lut [256]hsvcolor.NHSVA
+	for v := range lut {
+		vf := float64(v) / 255.0
+		var c hsvcolor.NHSVAF64
+		switch {
+		case vf <= midPoint:
+			c = hsvcolor.LerpNHSVAF64(sf, mf, vf/midPoint)
+		default:
+			c = hsvcolor.LerpNHSVAF64(mf, hf, (vf-midPoint)/(1.0-midPoint))
+		}
+		lut[v] = c.To8()
+	}
+
+	// Map each pixel's value to a color.
+	dst := NewNHSVA(p.Rect)
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		j := dst.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i, j = x+1, i+4, j+4 {
+			c := lut[p.Pix[i+2]]
+			d := dst.Pix[j : j+4 : j+4]
+			d[0] = c.H
+			d[1] = c.S
+			d[2] = c.V
+			d[3] = p.Pix[i+3]
+		}
+	}
+	return dst
+}
+
+// ValueToHue returns a false-color copy of an image in which each pixel's
+// value is encoded as a hue along a blue-to-red ramp: a value of 0 maps to
+// blue (240°) and a value of 255 maps to red (0°).  Every pixel is given full
+// saturation and full value so that its hue alone conveys its original value.
+// Alpha is preserved.
+func (p *NHSVA) ValueToHue() *NHSVA {
+	dst := NewNHSVA(p.Rect)
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		j := dst.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i, j = x+1, i+4, j+4 {
+			d := dst.Pix[j : j+4 : j+4]
+			d[0] = uint8((255*170 - int(p.Pix[i+2])*170 + 127) / 255)
+			d[1] = 255
+			d[2] = 255
+			d[3] = p.Pix[i+3]
+		}
+	}
+	return dst
+}
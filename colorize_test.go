@@ -0,0 +1,68 @@
+// This file tests recoloring HSV images.
+
+package hsvimage
+
+import (
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+	"math"
+	"testing"
+)
+
+// TestTritone confirms that the shadow, midpoint, and highlight values map
+// exactly to the three color stops.
+func TestTritone(t *testing.T) {
+	shadow := hsvcolor.NHSVA{H: 170, S: 200, V: 40, A: 255}
+	mid := hsvcolor.NHSVA{H: 20, S: 120, V: 150, A: 255}
+	highlight := hsvcolor.NHSVA{H: 40, S: 30, V: 250, A: 255}
+	img := NewNHSVA(image.Rect(0, 0, 4, 1))
+	img.SetNHSVA(0, 0, hsvcolor.NHSVA{H: 5, S: 5, V: 0, A: 255})
+	img.SetNHSVA(1, 0, hsvcolor.NHSVA{H: 90, S: 250, V: 100, A: 255})
+	img.SetNHSVA(2, 0, hsvcolor.NHSVA{H: 200, S: 0, V: 255, A: 255})
+	img.SetNHSVA(3, 0, hsvcolor.NHSVA{H: 200, S: 0, V: 180, A: 99})
+	tri := img.Tritone(shadow, mid, highlight, 100.0/255.0)
+	for x, want := range []hsvcolor.NHSVA{shadow, mid, highlight} {
+		if c := tri.NHSVAAt(x, 0); c != want {
+			t.Fatalf("Expected %v but saw %v at (%d, 0)", want, c, x)
+		}
+	}
+
+	// Values between stops should produce in-between colors while
+	// retaining the original alpha.
+	c := tri.NHSVAAt(3, 0)
+	if c.V <= mid.V || c.V >= highlight.V || c.S <= highlight.S || c.S >= mid.S || c.A != 99 {
+		t.Fatalf("Expected a color between %v and %v with alpha 99 but saw %v", mid, highlight, c)
+	}
+
+	// Degenerate midpoints should be clamped rather than produce NaNs.
+	for _, mp := range []float64{0.0, -1.0, 1.0, 2.0, math.NaN()} {
+		tri = img.Tritone(shadow, mid, highlight, mp)
+		if c := tri.NHSVAAt(0, 0); c != shadow {
+			t.Fatalf("Expected %v with midpoint %v but saw %v", shadow, mp, c)
+		}
+		if c := tri.NHSVAAt(2, 0); c != highlight {
+			t.Fatalf("Expected %v with midpoint %v but saw %v", highlight, mp, c)
+		}
+	}
+}
+
+// TestValueToHue confirms that dark pixels become blue, bright pixels become
+// red, and intermediate values fall in between.
+func TestValueToHue(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 256, 1))
+	for x := 0; x < 256; x++ {
+		img.SetNHSVA(x, 0, hsvcolor.NHSVA{H: 99, S: 99, V: uint8(x), A: 200})
+	}
+	vis := img.ValueToHue()
+	if c := vis.NHSVAAt(0, 0); c != (hsvcolor.NHSVA{H: 170, S: 255, V: 255, A: 200}) {
+		t.Fatalf("Expected the darkest pixel to be blue but saw %v", c)
+	}
+	if c := vis.NHSVAAt(255, 0); c != (hsvcolor.NHSVA{H: 0, S: 255, V: 255, A: 200}) {
+		t.Fatalf("Expected the brightest pixel to be red but saw %v", c)
+	}
+	for x := 1; x < 256; x++ {
+		if vis.NHSVAAt(x, 0).H > vis.NHSVAAt(x-1, 0).H {
+			t.Fatalf("Expected hue to decrease monotonically with value but saw an increase at %d", x)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package hsvimage
+
+import (
+	"fmt"
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+	"image/color"
+)
+
+// toNHSVA returns an image as an *NHSVA.  If the image is already an *NHSVA,
+// it is returned as is; otherwise, it is converted pixel by pixel.
+func toNHSVA(img image.Image) *NHSVA {
+	if p, ok := img.(*NHSVA); ok {
+		return p
+	}
+	r := img.Bounds()
+	p := NewNHSVA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			p.Set(x, y, img.At(x, y))
+		}
+	}
+	return p
+}
+
+// ToBGRA converts an image to a tightly packed buffer of non-alpha-premultiplied
+// 8-bit pixels in B, G, R, A order, as expected by, e.g., SDL's BGRA8888
+// format and Windows DIB sections.  Fully transparent pixels are converted to
+// all zeros.
+func (p *NHSVA) ToBGRA() []uint8 {
+	w, h := p.Rect.Dx(), p.Rect.Dy()
+	if w <= 0 || h <= 0 {
+		return nil
+	}
+	buf := make([]uint8, 4*w*h)
+	j := 0
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i, j = x+1, i+4, j+4 {
+			s := p.Pix[i : i+4 : i+4]
+			rp, gp, bp, a := hsvcolor.NHSVA{H: s[0], S: s[1], V: s[2], A: s[3]}.RGBA()
+			if a == 0 {
+				continue
+			}
+			ahalf := a / 2
+			d := buf[j : j+4 : j+4]
+			d[0] = uint8((255*bp + ahalf) / a)
+			d[1] = uint8((255*gp + ahalf) / a)
+			d[2] = uint8((255*rp + ahalf) / a)
+			d[3] = uint8(a >> 8)
+		}
+	}
+	return buf
+}
+
+// ToLumaGray converts an image to grayscale using each pixel's perceived
+// luma, as computed by hsvcolor.NHSVA's PerceivedLuma method, rather than its
+// value.  Alpha is ignored.
+func (p *NHSVA) ToLumaGray() *image.Gray {
+	dst := image.NewGray(p.Rect)
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		j := dst.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i, j = x+1, i+4, j+1 {
+			s := p.Pix[i : i+4 : i+4]
+			dst.Pix[j] = hsvcolor.NHSVA{H: s[0], S: s[1], V: s[2], A: s[3]}.PerceivedLuma()
+		}
+	}
+	return dst
+}
+
+// ToYCbCr converts an image to an *image.YCbCr with the same bounds and the
+// given chroma subsampling ratio.  Each pixel is converted to RGB, with
+// transparency treated as blending with black, and then to Y'CbCr with
+// color.RGBToYCbCr.  Each chroma sample is the mean of the chroma of the
+// pixels it covers.
+func (p *NHSVA) ToYCbCr(ratio image.YCbCrSubsampleRatio) *image.YCbCr {
+	dst := image.NewYCbCr(p.Rect, ratio)
+	cbSum := make([]uint32, len(dst.Cb))
+	crSum := make([]uint32, len(dst.Cr))
+	count := make([]uint32, len(dst.Cb))
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+			s := p.Pix[i : i+4 : i+4]
+			r, g, b, _ := hsvcolor.NHSVA{H: s[0], S: s[1], V: s[2], A: s[3]}.RGBA()
+			yy, cb, cr := color.RGBToYCbCr(uint8(r>>8), uint8(g>>8), uint8(b>>8))
+			dst.Y[dst.YOffset(x, y)] = yy
+			j := dst.COffset(x, y)
+			cbSum[j] += uint32(cb)
+			crSum[j] += uint32(cr)
+			count[j]++
+		}
+	}
+	for j, n := range count {
+		if n == 0 {
+			continue
+		}
+		dst.Cb[j] = uint8((cbSum[j] + n/2) / n)
+		dst.Cr[j] = uint8((crSum[j] + n/2) / n)
+	}
+	return dst
+}
+
+// FlattenOnto alpha-composites the image over a solid background color and
+// returns the result as an *image.RGBA with the same bounds.  Fully
+// transparent pixels take on the background color.  If the background color
+// is opaque, so is the result.
+func (p *NHSVA) FlattenOnto(bg color.Color) *image.RGBA {
+	bgR, bgG, bgB, bgA := bg.RGBA()
+	dst := image.NewRGBA(p.Rect)
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		j := dst.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i, j = x+1, i+4, j+4 {
+			s := p.Pix[i : i+4 : i+4]
+			d := dst.Pix[j : j+4 : j+4]
+			if s[3] == 0 {
+				d[0] = uint8(bgR >> 8)
+				d[1] = uint8(bgG >> 8)
+				d[2] = uint8(bgB >> 8)
+				d[3] = uint8(bgA >> 8)
+				continue
+			}
+			sr, sg, sb, sa := hsvcolor.NHSVA{H: s[0], S: s[1], V: s[2], A: s[3]}.RGBA()
+			ia := 0xffff - sa
+			d[0] = uint8((sr + bgR*ia/0xffff) >> 8)
+			d[1] = uint8((sg + bgG*ia/0xffff) >> 8)
+			d[2] = uint8((sb + bgB*ia/0xffff) >> 8)
+			d[3] = uint8((sa + bgA*ia/0xffff) >> 8)
+		}
+	}
+	return dst
+}
+
+// NewNHSVAFromPaletted converts an *image.Paletted to an *NHSVA with the same
+// bounds.  Each palette entry is converted to HSV only once, and pixels are
+// then mapped through the resulting lookup table, which is much faster than
+// converting each pixel individually.  Pixels whose index lies beyond the end
+// of the palette become transparent.
+func NewNHSVAFromPaletted(src *image.Paletted) *NHSVA {
+	var lut [256][4]uint8
+	for k, c := range src.Palette {
+		if k >= len(lut) {
+			break
+		}
+		hc := hsvcolor.NHSVAModel.Convert(c).(hsvcolor.NHSVA)
+		lut[k] = [4]uint8{hc.H, hc.S, hc.V, hc.A}
+	}
+	r := src.Rect
+	dst := NewNHSVA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := src.PixOffset(r.Min.X, y)
+		j := dst.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x, i, j = x+1, i+1, j+4 {
+			e := &lut[src.Pix[i]]
+			copy(dst.Pix[j:j+4:j+4], e[:])
+		}
+	}
+	return dst
+}
+
+// ConvertRGBA64Image converts an *image.RGBA64 to an *NHSVA64 with the same
+// bounds.  It produces exactly the same colors as hsvcolor.NHSVA64Model but
+// reads and writes pixel buffers directly, avoiding a color.Color interface
+// conversion per pixel.
+func ConvertRGBA64Image(src *image.RGBA64) *NHSVA64 {
+	r := src.Rect
+	dst := NewNHSVA64(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := src.PixOffset(r.Min.X, y)
+		j := dst.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x, i, j = x+1, i+8, j+8 {
+			s := src.Pix[i : i+8 : i+8]
+			d := dst.Pix[j : j+8 : j+8]
+			a := uint32(s[6])<<8 | uint32(s[7])
+			if a == 0 {
+				continue // Already all zeros
+			}
+
+			// Convert from premultiplied to non-premultiplied RGB.
+			rv := (uint32(s[0])<<8 | uint32(s[1])) * 65535 / a
+			gv := (uint32(s[2])<<8 | uint32(s[3])) * 65535 / a
+			bv := (uint32(s[4])<<8 | uint32(s[5])) * 65535 / a
+
+			// Compute saturation, value, and hue as in
+			// hsvcolor.NHSVA64Model.
+			cMin := rv
+			if gv < cMin {
+				cMin = gv
+			}
+			if bv < cMin {
+				cMin = bv
+			}
+			cMax := rv
+			if gv > cMax {
+				cMax = gv
+			}
+			if bv > cMax {
+				cMax = bv
+			}
+			delta := cMax - cMin
+			var h, sat uint32
+			if cMax > 0 {
+				sat = (65535 * delta) / cMax
+			}
+			if delta != 0 {
+				var h360 int
+				ri, gi, bi, di := int(rv), int(gv), int(bv), int(delta)
+				switch cMax {
+				case rv:
+					h360 = (60 * (gi - bi)) / di
+				case gv:
+					h360 = (60*(bi-ri))/di + 120
+				default:
+					h360 = (60*(ri-gi))/di + 240
+				}
+				h360 = (h360 + 360) % 360
+				h = uint32((h360*65535 + 180) / 360)
+			}
+			d[0], d[1] = uint8(h>>8), uint8(h)
+			d[2], d[3] = uint8(sat>>8), uint8(sat)
+			d[4], d[5] = uint8(cMax>>8), uint8(cMax)
+			d[6], d[7] = s[6], s[7]
+		}
+	}
+	return dst
+}
+
+// Split separates an image into four grayscale images, one per channel, each
+// with the same bounds as the original.  The gray levels of h, s, v, and a are
+// the hue, saturation, value, and alpha channels, respectively.
+func (p *NHSVA) Split() (h, s, v, a *image.Gray) {
+	planes := [4]*image.Gray{}
+	for c := range planes {
+		planes[c] = image.NewGray(p.Rect)
+	}
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		j := planes[0].PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i, j = x+1, i+4, j+1 {
+			for c, pl := range planes {
+				pl.Pix[j] = p.Pix[i+c]
+			}
+		}
+	}
+	return planes[0], planes[1], planes[2], planes[3]
+}
+
+// CombineNHSVA is the inverse of Split.  It constructs an image whose hue,
+// saturation, value, and alpha channels are taken from the gray levels of h,
+// s, v, and a, respectively.  CombineNHSVA returns an error if the four
+// images' bounds differ.
+func CombineNHSVA(h, s, v, a *image.Gray) (*NHSVA, error) {
+	r := h.Rect
+	for _, pl := range [...]*image.Gray{s, v, a} {
+		if !pl.Rect.Eq(r) {
+			return nil, fmt.Errorf("hsvimage: channel bounds %v and %v differ", r, pl.Rect)
+		}
+	}
+	dst := NewNHSVA(r)
+	planes := [4]*image.Gray{h, s, v, a}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := dst.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x, i = x+1, i+4 {
+			for c, pl := range planes {
+				dst.Pix[i+c] = pl.Pix[pl.PixOffset(x, y)]
+			}
+		}
+	}
+	return dst, nil
+}
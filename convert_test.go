@@ -0,0 +1,296 @@
+// This file tests conversions between HSV images and other image types.
+
+package hsvimage
+
+import (
+	"bytes"
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// TestToBGRA confirms that ToBGRA produces non-premultiplied BGRA bytes.
+func TestToBGRA(t *testing.T) {
+	colors := []hsvcolor.NHSVA{
+		{H: 0, S: 255, V: 255, A: 255},   // Red
+		{H: 85, S: 255, V: 255, A: 255},  // Green
+		{H: 170, S: 255, V: 255, A: 255}, // Blue
+		{H: 0, S: 0, V: 255, A: 255},     // White
+		{H: 0, S: 255, V: 255, A: 128},   // Half-transparent red
+		{H: 0, S: 0, V: 128, A: 64},      // Quarter-transparent gray
+		{H: 0, S: 255, V: 255, A: 0},     // Fully transparent red
+	}
+	expected := []uint8{
+		0, 0, 255, 255,
+		0, 255, 0, 255,
+		255, 0, 0, 255,
+		255, 255, 255, 255,
+		0, 0, 255, 128,
+		128, 128, 128, 64,
+		0, 0, 0, 0,
+	}
+	img := NewNHSVA(image.Rect(0, 0, 4, 3))
+	sub := img.SubImage(image.Rect(1, 1, 4, 3)).(*NHSVA)
+	for i, c := range colors[:6] {
+		sub.SetNHSVA(1+i%3, 1+i/3, c)
+	}
+	if buf := sub.ToBGRA(); !bytes.Equal(buf, expected[:24]) {
+		t.Fatalf("Expected %v but saw %v", expected[:24], buf)
+	}
+	one := NewNHSVA(image.Rect(0, 0, 1, 1))
+	one.SetNHSVA(0, 0, colors[6])
+	if buf := one.ToBGRA(); !bytes.Equal(buf, expected[24:]) {
+		t.Fatalf("Expected %v but saw %v", expected[24:], buf)
+	}
+}
+
+// TestToLumaGray confirms that ToLumaGray renders yellow brighter than blue.
+func TestToLumaGray(t *testing.T) {
+	img := NewNHSVA(image.Rect(2, 3, 5, 4))
+	img.SetNHSVA(2, 3, hsvcolor.NHSVA{H: 43, S: 255, V: 255, A: 255})  // Yellow
+	img.SetNHSVA(3, 3, hsvcolor.NHSVA{H: 170, S: 255, V: 255, A: 255}) // Blue
+	img.SetNHSVA(4, 3, hsvcolor.NHSVA{H: 0, S: 0, V: 100, A: 255})     // Gray
+	gray := img.ToLumaGray()
+	if !gray.Rect.Eq(img.Rect) {
+		t.Fatalf("Expected bounds %v but saw %v", img.Rect, gray.Rect)
+	}
+	yellow, blue := gray.GrayAt(2, 3).Y, gray.GrayAt(3, 3).Y
+	if yellow <= blue {
+		t.Fatalf("Expected yellow (%d) to be brighter than blue (%d)", yellow, blue)
+	}
+	if g := gray.GrayAt(4, 3).Y; g != 100 {
+		t.Fatalf("Expected a gray of 100 to be left alone but saw %d", g)
+	}
+}
+
+// TestToYCbCr confirms that ToYCbCr agrees with the standard library's
+// conversion for each chroma subsampling ratio.
+func TestToYCbCr(t *testing.T) {
+	c := hsvcolor.NHSVA{H: 20, S: 180, V: 220, A: 255}
+	img := NewNHSVA(image.Rect(1, 2, 9, 8))
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			img.SetNHSVA(x, y, c)
+		}
+	}
+	r, g, b, _ := c.RGBA()
+	ey, ecb, ecr := color.RGBToYCbCr(uint8(r>>8), uint8(g>>8), uint8(b>>8))
+	for _, ratio := range []image.YCbCrSubsampleRatio{
+		image.YCbCrSubsampleRatio444,
+		image.YCbCrSubsampleRatio422,
+		image.YCbCrSubsampleRatio420,
+		image.YCbCrSubsampleRatio440,
+		image.YCbCrSubsampleRatio411,
+		image.YCbCrSubsampleRatio410,
+	} {
+		ycc := img.ToYCbCr(ratio)
+		if !ycc.Rect.Eq(img.Rect) || ycc.SubsampleRatio != ratio {
+			t.Fatalf("%v: Expected bounds %v but saw %v", ratio, img.Rect, ycc.Rect)
+		}
+		for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+			for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+				if got := ycc.YCbCrAt(x, y); got != (color.YCbCr{ey, ecb, ecr}) {
+					t.Fatalf("%v: Expected %v but saw %v at (%d, %d)", ratio, color.YCbCr{ey, ecb, ecr}, got, x, y)
+				}
+			}
+		}
+	}
+
+	// Chroma should be averaged over each subsampled block.
+	img.SetNHSVA(2, 2, hsvcolor.NHSVA{H: 170, S: 255, V: 255, A: 255})
+	ycc := img.ToYCbCr(image.YCbCrSubsampleRatio420)
+	_, bcb, _ := color.RGBToYCbCr(0, 0, 255)
+	want := uint8((3*uint32(ecb) + uint32(bcb) + 2) / 4)
+	if cb := ycc.Cb[ycc.COffset(3, 3)]; cb != want {
+		t.Fatalf("Expected an averaged Cb of %d but saw %d", want, cb)
+	}
+}
+
+// TestFlattenOnto confirms that FlattenOnto composites an image over a solid
+// background color.
+func TestFlattenOnto(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 3, 1))
+	img.SetNHSVA(0, 0, hsvcolor.NHSVA{H: 0, S: 255, V: 255, A: 128})  // Half-transparent red
+	img.SetNHSVA(1, 0, hsvcolor.NHSVA{H: 85, S: 255, V: 255, A: 255}) // Opaque green
+	img.SetNHSVA(2, 0, hsvcolor.NHSVA{H: 170, S: 255, V: 255, A: 0})  // Fully transparent blue
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	flat := img.FlattenOnto(white)
+	if !flat.Bounds().Eq(img.Bounds()) {
+		t.Fatalf("Expected bounds %v but saw %v", img.Bounds(), flat.Bounds())
+	}
+	expected := []color.RGBA{
+		{R: 255, G: 127, B: 127, A: 255}, // Pink
+		{R: 0, G: 255, B: 0, A: 255},
+		white,
+	}
+	for x, e := range expected {
+		if c := flat.RGBAAt(x, 0); c != e {
+			t.Fatalf("Expected %v but saw %v at (%d, 0)", e, c, x)
+		}
+	}
+	if !flat.Opaque() {
+		t.Fatal("Expected an opaque result")
+	}
+}
+
+// newTestPaletted returns an *image.Paletted with a 216-color web-safe
+// palette and a varied pattern of indexes.
+func newTestPaletted(r image.Rectangle) *image.Paletted {
+	pal := make(color.Palette, 0, 216)
+	for rr := 0; rr < 6; rr++ {
+		for gg := 0; gg < 6; gg++ {
+			for bb := 0; bb < 6; bb++ {
+				pal = append(pal, color.NRGBA{uint8(rr * 51), uint8(gg * 51), uint8(bb * 51), uint8(255 - bb*20)})
+			}
+		}
+	}
+	img := image.NewPaletted(r, pal)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetColorIndex(x, y, uint8((x*7+y*13)%len(pal)))
+		}
+	}
+	return img
+}
+
+// TestNewNHSVAFromPaletted confirms that NewNHSVAFromPaletted agrees with
+// per-pixel conversion.
+func TestNewNHSVAFromPaletted(t *testing.T) {
+	src := newTestPaletted(image.Rect(-5, 3, 60, 40))
+	sub := src.SubImage(image.Rect(0, 10, 50, 30)).(*image.Paletted)
+	dst := NewNHSVAFromPaletted(sub)
+	if !dst.Rect.Eq(sub.Rect) {
+		t.Fatalf("Expected bounds %v but saw %v", sub.Rect, dst.Rect)
+	}
+	for y := sub.Rect.Min.Y; y < sub.Rect.Max.Y; y++ {
+		for x := sub.Rect.Min.X; x < sub.Rect.Max.X; x++ {
+			exp := hsvcolor.NHSVAModel.Convert(sub.At(x, y)).(hsvcolor.NHSVA)
+			if c := dst.NHSVAAt(x, y); c != exp {
+				t.Fatalf("Expected %v but saw %v at (%d, %d)", exp, c, x, y)
+			}
+		}
+	}
+}
+
+// BenchmarkNewNHSVAFromPaletted measures the speed of converting an
+// *image.Paletted with NewNHSVAFromPaletted.
+func BenchmarkNewNHSVAFromPaletted(b *testing.B) {
+	src := newTestPaletted(image.Rect(0, 0, 512, 512))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewNHSVAFromPaletted(src)
+	}
+}
+
+// BenchmarkNewNHSVAFromPalettedGeneric measures the speed of converting an
+// *image.Paletted pixel by pixel through the color.Color interface.
+func BenchmarkNewNHSVAFromPalettedGeneric(b *testing.B) {
+	src := newTestPaletted(image.Rect(0, 0, 512, 512))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dst := NewNHSVA(src.Rect)
+		for y := src.Rect.Min.Y; y < src.Rect.Max.Y; y++ {
+			for x := src.Rect.Min.X; x < src.Rect.Max.X; x++ {
+				dst.Set(x, y, src.At(x, y))
+			}
+		}
+	}
+}
+
+// newRGBA64Gradient returns an *image.RGBA64 filled with a variety of colors
+// and alphas.
+func newRGBA64Gradient(r image.Rectangle) *image.RGBA64 {
+	img := image.NewRGBA64(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			a := uint16(65535 - (y*251)%65536)
+			nc := color.NRGBA64{
+				R: uint16(x * 257),
+				G: uint16(y * 193),
+				B: uint16((x + y) * 127),
+				A: a,
+			}
+			img.Set(x, y, nc)
+		}
+	}
+	return img
+}
+
+// TestConvertRGBA64Image confirms that ConvertRGBA64Image agrees with the
+// hsvcolor.NHSVA64Model color model.
+func TestConvertRGBA64Image(t *testing.T) {
+	src := newRGBA64Gradient(image.Rect(-3, -5, 253, 251))
+	src.Set(0, 0, color.RGBA64{})                                       // Fully transparent
+	src.Set(1, 0, color.RGBA64{R: 30000, G: 30000, B: 30000, A: 40000}) // Gray
+	sub := src.SubImage(image.Rect(-2, -4, 200, 220)).(*image.RGBA64)
+	dst := ConvertRGBA64Image(sub)
+	if !dst.Rect.Eq(sub.Rect) {
+		t.Fatalf("Expected bounds %v but saw %v", sub.Rect, dst.Rect)
+	}
+	for y := sub.Rect.Min.Y; y < sub.Rect.Max.Y; y++ {
+		for x := sub.Rect.Min.X; x < sub.Rect.Max.X; x++ {
+			exp := hsvcolor.NHSVA64Model.Convert(sub.At(x, y)).(hsvcolor.NHSVA64)
+			if c := dst.NHSVA64At(x, y); c != exp {
+				t.Fatalf("Expected %v but saw %v at (%d, %d)", exp, c, x, y)
+			}
+		}
+	}
+}
+
+// BenchmarkConvertRGBA64Image measures the speed of converting an
+// *image.RGBA64 with ConvertRGBA64Image.
+func BenchmarkConvertRGBA64Image(b *testing.B) {
+	src := newRGBA64Gradient(image.Rect(0, 0, 512, 512))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ConvertRGBA64Image(src)
+	}
+}
+
+// BenchmarkConvertRGBA64ImageGeneric measures the speed of converting an
+// *image.RGBA64 pixel by pixel through the color.Color interface.
+func BenchmarkConvertRGBA64ImageGeneric(b *testing.B) {
+	src := newRGBA64Gradient(image.Rect(0, 0, 512, 512))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dst := NewNHSVA64(src.Rect)
+		for y := src.Rect.Min.Y; y < src.Rect.Max.Y; y++ {
+			for x := src.Rect.Min.X; x < src.Rect.Max.X; x++ {
+				dst.Set(x, y, src.At(x, y))
+			}
+		}
+	}
+}
+
+// TestSplitCombine confirms that an image survives a round trip through Split
+// and CombineNHSVA.
+func TestSplitCombine(t *testing.T) {
+	img := NewNHSVA(image.Rect(-2, -3, 10, 9))
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: uint8(x * 21), S: uint8(y * 17), V: uint8(x * y), A: uint8(x + y + 5)})
+		}
+	}
+	sub := img.SubImage(image.Rect(0, 0, 6, 5)).(*NHSVA)
+	h, s, v, a := sub.Split()
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 6; x++ {
+			c := sub.NHSVAAt(x, y)
+			if h.GrayAt(x, y).Y != c.H || s.GrayAt(x, y).Y != c.S || v.GrayAt(x, y).Y != c.V || a.GrayAt(x, y).Y != c.A {
+				t.Fatalf("Expected %v but saw {%d %d %d %d} at (%d, %d)", c,
+					h.GrayAt(x, y).Y, s.GrayAt(x, y).Y, v.GrayAt(x, y).Y, a.GrayAt(x, y).Y, x, y)
+			}
+		}
+	}
+	back, err := CombineNHSVA(h, s, v, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !back.Equal(sub) {
+		t.Fatalf("Expected %v but saw %v", sub, back)
+	}
+	if _, err = CombineNHSVA(h, s, v, image.NewGray(image.Rect(0, 0, 6, 4))); err == nil {
+		t.Fatal("Expected an error when combining images with different bounds")
+	}
+}
@@ -0,0 +1,59 @@
+package hsvimage
+
+import (
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+	"image/color"
+)
+
+// QuantizeToPalette maps each pixel of an image to the nearest entry in an
+// HSV palette, as determined by hsvcolor.NearestNHSVA, and returns the result
+// as an *image.Paletted whose palette holds the RGBA equivalents of the HSV
+// entries.  Quantization error in the value channel is diffused to
+// neighboring pixels using Floyd–Steinberg dithering so that smooth gradients
+// are rendered as patterns of palette colors rather than as hard bands.  The
+// result is deterministic.  QuantizeToPalette panics if the palette is empty
+// or contains more than 256 entries.
+func QuantizeToPalette(src *NHSVA, palette []hsvcolor.NHSVA) *image.Paletted {
+	if len(palette) == 0 || len(palette) > 256 {
+		panic("hsvimage: palette must contain between 1 and 256 colors")
+	}
+	pal := make(color.Palette, len(palette))
+	for i, c := range palette {
+		pal[i] = color.RGBAModel.Convert(c)
+	}
+	r := src.Rect
+	dst := image.NewPaletted(r, pal)
+
+	// Diffuse error into the current row and the next, padding each with
+	// an extra element on either side to avoid edge checks.
+	w := r.Dx()
+	cur := make([]float64, w+2)
+	next := make([]float64, w+2)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := src.PixOffset(r.Min.X, y)
+		j := dst.PixOffset(r.Min.X, y)
+		for x, k := r.Min.X, 1; x < r.Max.X; x, i, j, k = x+1, i+4, j+1, k+1 {
+			s := src.Pix[i : i+4 : i+4]
+			v := float64(s[2]) + cur[k]
+			if v < 0.0 {
+				v = 0.0
+			} else if v > 255.0 {
+				v = 255.0
+			}
+			target := hsvcolor.NHSVA{H: s[0], S: s[1], V: clampUint8(v), A: s[3]}
+			idx := hsvcolor.NearestNHSVA(target, palette)
+			dst.Pix[j] = uint8(idx)
+			e := v - float64(palette[idx].V)
+			cur[k+1] += e * 7.0 / 16.0
+			next[k-1] += e * 3.0 / 16.0
+			next[k] += e * 5.0 / 16.0
+			next[k+1] += e * 1.0 / 16.0
+		}
+		cur, next = next, cur
+		for k := range next {
+			next[k] = 0.0
+		}
+	}
+	return dst
+}
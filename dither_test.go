@@ -0,0 +1,70 @@
+// This file tests palette quantization of HSV images.
+
+package hsvimage
+
+import (
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// TestQuantizeToPalette confirms that QuantizeToPalette dithers a smooth value
+// gradient into a mixture of palette indices rather than a hard band.
+func TestQuantizeToPalette(t *testing.T) {
+	const wd, ht = 256, 16
+	img := NewNHSVA(image.Rect(0, 0, wd, ht))
+	for y := 0; y < ht; y++ {
+		for x := 0; x < wd; x++ {
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: 0, S: 0, V: uint8(x), A: 255})
+		}
+	}
+	palette := []hsvcolor.NHSVA{
+		{H: 0, S: 0, V: 0, A: 255},   // Black
+		{H: 0, S: 0, V: 255, A: 255}, // White
+	}
+	pimg := QuantizeToPalette(img, palette)
+	if !pimg.Rect.Eq(img.Rect) {
+		t.Fatalf("Expected bounds %v but saw %v", img.Rect, pimg.Rect)
+	}
+	if c := pimg.Palette[1]; c != (color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Fatalf("Expected a white palette entry but saw %v", c)
+	}
+
+	// Within each block of columns, the fraction of white pixels should
+	// track the mean value.
+	const blk = 32
+	for x0 := 0; x0 < wd; x0 += blk {
+		n := 0
+		for y := 0; y < ht; y++ {
+			for x := x0; x < x0+blk; x++ {
+				n += int(pimg.ColorIndexAt(x, y))
+			}
+		}
+		frac := float64(n) / float64(blk*ht)
+		want := (float64(x0) + float64(blk-1)/2.0) / 255.0
+		if math.Abs(frac-want) > 0.05 {
+			t.Fatalf("Expected about %.3f white in columns %d-%d but saw %.3f", want, x0, x0+blk-1, frac)
+		}
+	}
+
+	// The middle of each row should alternate between indices.
+	changes := 0
+	for x := 96; x < 160; x++ {
+		if pimg.ColorIndexAt(x, ht/2) != pimg.ColorIndexAt(x+1, ht/2) {
+			changes++
+		}
+	}
+	if changes < 16 {
+		t.Fatalf("Expected the middle of the gradient to alternate indices but saw only %d changes", changes)
+	}
+
+	// Dithering should be deterministic.
+	again := QuantizeToPalette(img, palette)
+	for i := range pimg.Pix {
+		if pimg.Pix[i] != again.Pix[i] {
+			t.Fatal("Expected repeated quantization to produce identical results")
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package hsvimage
+
+import (
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+	"math"
+	"sort"
+)
+
+// blendOverF64 composites src, with its alpha scaled by coverage, over dst
+// using the Porter-Duff "over" operator.  Saturation and value are mixed
+// linearly; hue is mixed on the color wheel, weighted by saturation.
+func blendOverF64(dst, src hsvcolor.NHSVAF64, coverage float64) hsvcolor.NHSVAF64 {
+	sa := src.A * coverage
+	switch {
+	case sa <= 0.0:
+		return dst
+	case sa >= 1.0 || dst.A <= 0.0:
+		src.A = sa
+		return src
+	}
+	ws := sa
+	wd := dst.A * (1.0 - sa)
+	wt := ws + wd
+	var hs hueSum
+	hs.add(src.H, ws*src.S)
+	hs.add(dst.H, wd*dst.S)
+	return hsvcolor.NHSVAF64{
+		H: hs.mean(),
+		S: (ws*src.S + wd*dst.S) / wt,
+		V: (ws*src.V + wd*dst.V) / wt,
+		A: wt,
+	}
+}
+
+// DrawLine draws an anti-aliased line segment of the given width from (x0, y0)
+// to (x1, y1) by alpha-blending c onto the image.  Coordinates refer to pixel
+// edges, so pixel (x, y) is centered at (x+0.5, y+0.5).  Each pixel's coverage
+// is estimated from the distance between its center and the segment, which
+// produces smoothly anti-aliased edges and rounded endpoints.  Only the span
+// of each row that lies within reach of the segment is examined, so the cost
+// is proportional to the area of the line, not of its bounding box.  Pixels
+// outside the image bounds are not touched.
+func (p *NHSVAF64) DrawLine(x0, y0, x1, y1 float64, c hsvcolor.NHSVAF64, width float64) {
+	// Determine the range of rows that may be affected.  A pixel is
+	// touched only if its center lies within r of the segment.
+	hw := width / 2.0
+	r := hw + 0.5
+	yMin := int(math.Floor(math.Min(y0, y1) - r))
+	yMax := int(math.Ceil(math.Max(y0, y1) + r))
+	if yMin < p.Rect.Min.Y {
+		yMin = p.Rect.Min.Y
+	}
+	if yMax > p.Rect.Max.Y {
+		yMax = p.Rect.Max.Y
+	}
+
+	// Blend the color into each pixel in proportion to its coverage.
+	dx, dy := x1-x0, y1-y0
+	len2 := dx*dx + dy*dy
+	for y := yMin; y < yMax; y++ {
+		// Find the portion of the segment within r of the row's
+		// centerline and from that the span of pixels to examine.
+		py := float64(y) + 0.5
+		t0, t1 := 0.0, 1.0
+		if dy != 0.0 {
+			t0, t1 = (py-r-y0)/dy, (py+r-y0)/dy
+			if t0 > t1 {
+				t0, t1 = t1, t0
+			}
+			t0, t1 = math.Max(t0, 0.0), math.Min(t1, 1.0)
+			if t0 > t1 {
+				continue
+			}
+		}
+		xa, xb := x0+t0*dx, x0+t1*dx
+		xMin := int(math.Floor(math.Min(xa, xb) - r))
+		xMax := int(math.Ceil(math.Max(xa, xb) + r))
+		if xMin < p.Rect.Min.X {
+			xMin = p.Rect.Min.X
+		}
+		if xMax > p.Rect.Max.X {
+			xMax = p.Rect.Max.X
+		}
+		for x := xMin; x < xMax; x++ {
+			// Find the distance from the pixel center to the
+			// nearest point on the segment.
+			px := float64(x) + 0.5
+			t := 0.0
+			if len2 > 0.0 {
+				t = ((px-x0)*dx + (py-y0)*dy) / len2
+				t = math.Max(0.0, math.Min(1.0, t))
+			}
+			d := math.Hypot(px-(x0+t*dx), py-(y0+t*dy))
+
+			// Convert distance to coverage and blend.
+			cov := math.Min(1.0, r-d)
+			if cov <= 0.0 {
+				continue
+			}
+			p.SetNHSVAF64(x, y, blendOverF64(p.NHSVAF64At(x, y), c, cov))
+		}
+	}
+}
+
+// FillPolygon alpha-blends c onto every pixel whose center lies within the
+// polygon with the given vertices.  As in DrawLine, pixel (x, y) is centered
+// at (x+0.5, y+0.5).  Interior points are determined using the even-odd rule,
+// so non-convex and self-intersecting polygons are supported.  Pixels outside
+// the image bounds are not touched.
+func (p *NHSVAF64) FillPolygon(pts []image.Point, c hsvcolor.NHSVAF64) {
+	if len(pts) < 3 {
+		return
+	}
+	xs := make([]float64, 0, len(pts))
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		// Find where each edge crosses the current scanline.
+		yc := float64(y) + 0.5
+		xs = xs[:0]
+		for i, a := range pts {
+			b := pts[(i+1)%len(pts)]
+			ay, by := float64(a.Y), float64(b.Y)
+			if (ay <= yc) == (by <= yc) {
+				continue
+			}
+			t := (yc - ay) / (by - ay)
+			xs = append(xs, float64(a.X)+t*float64(b.X-a.X))
+		}
+		sort.Float64s(xs)
+
+		// Fill between alternate pairs of crossings.
+		for i := 0; i+1 < len(xs); i += 2 {
+			x0 := int(math.Ceil(xs[i] - 0.5))
+			x1 := int(math.Ceil(xs[i+1] - 0.5))
+			if x0 < p.Rect.Min.X {
+				x0 = p.Rect.Min.X
+			}
+			if x1 > p.Rect.Max.X {
+				x1 = p.Rect.Max.X
+			}
+			for x := x0; x < x1; x++ {
+				p.SetNHSVAF64(x, y, blendOverF64(p.NHSVAF64At(x, y), c, 1.0))
+			}
+		}
+	}
+}
+
+// fillRect sets every pixel within r (clipped to the image bounds) to c.
+func (p *NHSVA) fillRect(r image.Rectangle, c hsvcolor.NHSVA) {
+	r = r.Intersect(p.Rect)
+	if r.Empty() {
+		return
+	}
+	if c.A != 0xff {
+		p.opaqueHint = false
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := p.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x, i = x+1, i+4 {
+			s := p.Pix[i : i+4 : i+4]
+			s[0], s[1], s[2], s[3] = c.H, c.S, c.V, c.A
+		}
+	}
+}
+
+// DrawRectBorder strokes the outline of r with color c, clipped to the image
+// bounds.  The border lies entirely inside r and is thickness pixels wide.  If
+// the border is too thick to leave an interior, the whole of r is filled.
+// Pixels are replaced, not blended.  A non-positive thickness draws nothing.
+func (p *NHSVA) DrawRectBorder(r image.Rectangle, c hsvcolor.NHSVA, thickness int) {
+	r = r.Canon()
+	if thickness <= 0 || r.Empty() {
+		return
+	}
+	if 2*thickness >= r.Dx() || 2*thickness >= r.Dy() {
+		p.fillRect(r, c)
+		return
+	}
+	t := thickness
+	p.fillRect(image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+t), c)     // Top
+	p.fillRect(image.Rect(r.Min.X, r.Max.Y-t, r.Max.X, r.Max.Y), c)     // Bottom
+	p.fillRect(image.Rect(r.Min.X, r.Min.Y+t, r.Min.X+t, r.Max.Y-t), c) // Left
+	p.fillRect(image.Rect(r.Max.X-t, r.Min.Y+t, r.Max.X, r.Max.Y-t), c) // Right
+}
@@ -0,0 +1,176 @@
+// This file tests drawing onto HSV images.
+
+package hsvimage
+
+import (
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+	"math"
+	"testing"
+)
+
+// TestDrawLine confirms that a horizontal line covers the expected pixels and
+// that its endpoints are anti-aliased.
+func TestDrawLine(t *testing.T) {
+	img := NewNHSVAF64(image.Rect(0, 0, 12, 12))
+	red := hsvcolor.NHSVAF64{H: 0.0, S: 1.0, V: 1.0, A: 1.0}
+	img.DrawLine(3.0, 5.5, 9.0, 5.5, red, 1.0)
+	for y := 0; y < 12; y++ {
+		for x := 0; x < 12; x++ {
+			a := img.NHSVAF64At(x, y).A
+			switch {
+			case y == 5 && x >= 3 && x < 9:
+				if a != 1.0 {
+					t.Fatalf("Expected (%d, %d) to be fully covered but saw alpha %.3f", x, y, a)
+				}
+			case y == 5 && (x == 2 || x == 9):
+				if a <= 0.0 || a >= 1.0 {
+					t.Fatalf("Expected (%d, %d) to be partially covered but saw alpha %.3f", x, y, a)
+				}
+			default:
+				if a != 0.0 {
+					t.Fatalf("Expected (%d, %d) to be uncovered but saw alpha %.3f", x, y, a)
+				}
+			}
+		}
+	}
+
+	// Lines extending beyond the image must be clipped.  Only pixels
+	// within the line's reach of the diagonal should be covered.
+	img = NewNHSVAF64(image.Rect(-2, -2, 10, 10))
+	img.DrawLine(-20.0, -20.0, 40.0, 40.0, red, 3.0)
+	for y := -2; y < 10; y++ {
+		for x := -2; x < 10; x++ {
+			a := img.NHSVAF64At(x, y).A
+			d := x - y
+			if d < 0 {
+				d = -d
+			}
+			switch d {
+			case 0, 1:
+				if a != 1.0 {
+					t.Fatalf("Expected (%d, %d) to be fully covered but saw alpha %.3f", x, y, a)
+				}
+			case 2:
+				if a <= 0.0 || a >= 1.0 {
+					t.Fatalf("Expected (%d, %d) to be partially covered but saw alpha %.3f", x, y, a)
+				}
+			default:
+				if a != 0.0 {
+					t.Fatalf("Expected (%d, %d) to be uncovered but saw alpha %.3f", x, y, a)
+				}
+			}
+		}
+	}
+}
+
+// TestDrawLineSlanted confirms that restricting DrawLine to the pixels near a
+// slanted line touches exactly the pixels within reach of the segment.
+func TestDrawLineSlanted(t *testing.T) {
+	green := hsvcolor.NHSVAF64{H: 120.0, S: 1.0, V: 1.0, A: 1.0}
+	for _, ln := range [][5]float64{
+		{1.0, 2.0, 30.0, 9.0, 1.0},    // Shallow
+		{3.5, 30.0, 8.25, 1.0, 2.5},   // Steep
+		{30.0, 28.0, 2.0, 3.0, 4.0},   // Diagonal, reversed
+		{12.0, 12.0, 12.0, 12.0, 5.0}, // Point
+	} {
+		img := NewNHSVAF64(image.Rect(0, 0, 32, 32))
+		x0, y0, x1, y1, wd := ln[0], ln[1], ln[2], ln[3], ln[4]
+		img.DrawLine(x0, y0, x1, y1, green, wd)
+		dx, dy := x1-x0, y1-y0
+		for y := 0; y < 32; y++ {
+			for x := 0; x < 32; x++ {
+				px, py := float64(x)+0.5, float64(y)+0.5
+				tt := 0.0
+				if dx != 0.0 || dy != 0.0 {
+					tt = ((px-x0)*dx + (py-y0)*dy) / (dx*dx + dy*dy)
+					tt = math.Max(0.0, math.Min(1.0, tt))
+				}
+				d := math.Hypot(px-(x0+tt*dx), py-(y0+tt*dy))
+				cov := math.Max(0.0, math.Min(1.0, wd/2.0+0.5-d))
+				if a := img.NHSVAF64At(x, y).A; math.Abs(a-cov) > 1e-12 {
+					t.Fatalf("Expected alpha %.3f but saw %.3f at (%d, %d) for line %v", cov, a, x, y, ln)
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkDrawLine measures the time to draw a long diagonal line.
+func BenchmarkDrawLine(b *testing.B) {
+	img := NewNHSVAF64(image.Rect(0, 0, 1024, 1024))
+	red := hsvcolor.NHSVAF64{H: 0.0, S: 1.0, V: 1.0, A: 1.0}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		img.DrawLine(0.0, 0.0, 1024.0, 1024.0, red, 2.0)
+	}
+}
+
+// TestFillPolygon confirms that a filled triangle covers exactly the pixels
+// whose centers lie within it.
+func TestFillPolygon(t *testing.T) {
+	img := NewNHSVAF64(image.Rect(-2, -2, 12, 12))
+	blue := hsvcolor.NHSVAF64{H: 240.0, S: 1.0, V: 1.0, A: 1.0}
+	tri := []image.Point{{0, 0}, {10, 0}, {0, 10}}
+	img.FillPolygon(tri, blue)
+	for y := -2; y < 12; y++ {
+		for x := -2; x < 12; x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			inside := px > 0.0 && py > 0.0 && px+py < 10.0
+			c := img.NHSVAF64At(x, y)
+			if inside && c != blue {
+				t.Fatalf("Expected interior pixel (%d, %d) to be %v but saw %v", x, y, blue, c)
+			}
+			if !inside && c.A != 0.0 {
+				t.Fatalf("Expected exterior pixel (%d, %d) to be untouched but saw %v", x, y, c)
+			}
+		}
+	}
+
+	// A non-convex, clipped polygon must not panic.
+	img.FillPolygon([]image.Point{{-5, -5}, {20, 3}, {4, 4}, {3, 20}}, blue)
+}
+
+// TestDrawRectBorder confirms that DrawRectBorder sets border pixels, leaves
+// interior and exterior pixels alone, and fills rectangles too small to have
+// an interior.
+func TestDrawRectBorder(t *testing.T) {
+	bg := hsvcolor.NHSVA{H: 10, S: 20, V: 30, A: 40}
+	fg := hsvcolor.NHSVA{H: 85, S: 255, V: 255, A: 255}
+	for _, tc := range []struct {
+		r         image.Rectangle
+		thickness int
+	}{
+		{image.Rect(2, 3, 15, 12), 1},
+		{image.Rect(2, 3, 15, 12), 3},
+		{image.Rect(-5, -5, 8, 8), 2}, // Clipped
+		{image.Rect(4, 4, 10, 9), 3},  // Filled
+		{image.Rect(15, 12, 2, 3), 2}, // Non-canonical
+		{image.Rect(2, 3, 15, 12), 0}, // Nothing
+	} {
+		img := NewNHSVA(image.Rect(0, 0, 20, 16))
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 20; x++ {
+				img.SetNHSVA(x, y, bg)
+			}
+		}
+		img.DrawRectBorder(tc.r, fg, tc.thickness)
+		r := tc.r.Canon()
+		inner := r.Inset(tc.thickness)
+		if 2*tc.thickness >= r.Dx() || 2*tc.thickness >= r.Dy() {
+			inner = image.Rectangle{}
+		}
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 20; x++ {
+				pt := image.Pt(x, y)
+				exp := bg
+				if tc.thickness > 0 && pt.In(r) && !pt.In(inner) {
+					exp = fg
+				}
+				if c := img.NHSVAAt(x, y); c != exp {
+					t.Fatalf("%v, %d: Expected %v but saw %v at (%d, %d)", tc.r, tc.thickness, exp, c, x, y)
+				}
+			}
+		}
+	}
+}
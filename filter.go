@@ -0,0 +1,310 @@
+package hsvimage
+
+import (
+	"image"
+	"math"
+)
+
+// clampInt clamps an integer to [lo, hi].
+func clampInt(i, lo, hi int) int {
+	switch {
+	case i < lo:
+		return lo
+	case i > hi:
+		return hi
+	default:
+		return i
+	}
+}
+
+// boxBlur applies a separable box filter of the given radius to a w×h grid of
+// values stored in row-major order.  Samples that lie beyond the grid's edges
+// are replaced by the nearest edge sample.  Each pass maintains a running sum,
+// so the cost per value is independent of r.  boxBlur returns a new slice.
+func boxBlur(vals []float64, w, h, r int) []float64 {
+	out := make([]float64, len(vals))
+	copy(out, vals)
+	if r <= 0 || w <= 0 || h <= 0 {
+		return out
+	}
+	norm := 1.0 / float64(2*r+1)
+
+	// Blur horizontally.
+	tmp := make([]float64, len(vals))
+	for y := 0; y < h; y++ {
+		row := vals[y*w : (y+1)*w]
+		var sum float64
+		for k := -r; k <= r; k++ {
+			sum += row[clampInt(k, 0, w-1)]
+		}
+		for x := 0; x < w; x++ {
+			tmp[y*w+x] = sum * norm
+			sum += row[clampInt(x+r+1, 0, w-1)] - row[clampInt(x-r, 0, w-1)]
+		}
+	}
+
+	// Blur vertically.
+	for x := 0; x < w; x++ {
+		var sum float64
+		for k := -r; k <= r; k++ {
+			sum += tmp[clampInt(k, 0, h-1)*w+x]
+		}
+		for y := 0; y < h; y++ {
+			out[y*w+x] = sum * norm
+			sum += tmp[clampInt(y+r+1, 0, h-1)*w+x] - tmp[clampInt(y-r, 0, h-1)*w+x]
+		}
+	}
+	return out
+}
+
+// OrtonEffect returns a copy of an image with an Orton-style dreamy glow.  The
+// glow is produced by brightening the value channel (by screening it with
+// itself), blurring the result with a box filter of radius blurRadius, and
+// screening that back onto the original value channel.  blend, in [0, 1],
+// selects how much of the glow to apply.  Because only the value channel is
+// modified, hue, saturation, and alpha are preserved.
+func (p *NHSVA) OrtonEffect(blurRadius int, blend float64) *NHSVA {
+	// Extract a brightened copy of the value channel.
+	w, h := p.Rect.Dx(), p.Rect.Dy()
+	dst := NewNHSVA(p.Rect)
+	if w <= 0 || h <= 0 {
+		return dst
+	}
+	bright := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		i := p.PixOffset(p.Rect.Min.X, p.Rect.Min.Y+y)
+		copy(dst.Pix[y*dst.Stride:], p.Pix[i:i+w*4])
+		for x := 0; x < w; x, i = x+1, i+4 {
+			v := float64(p.Pix[i+2]) / 255.0
+			bright[y*w+x] = 1.0 - (1.0-v)*(1.0-v)
+		}
+	}
+
+	// Blur the brightened copy and screen it onto the original.
+	glow := boxBlur(bright, w, h, blurRadius)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*dst.Stride + x*4
+			v := float64(dst.Pix[i+2]) / 255.0
+			scr := 1.0 - (1.0-v)*(1.0-glow[y*w+x])
+			dst.Pix[i+2] = clampUint8((v + blend*(scr-v)) * 255.0)
+		}
+	}
+	return dst
+}
+
+// HueConvolve replaces each pixel's hue with a weighted circular mean of the
+// hues in its neighborhood.  kernel holds kw×kh weights in row-major order,
+// centered on the pixel at (kw/2, kh/2).  Each neighbor's weight is further
+// multiplied by its saturation so that gray pixels, whose hue is meaningless,
+// have no influence.  Neighbors beyond the image's edges are replaced by the
+// nearest edge pixel.  Saturation, value, and alpha are left untouched, as is
+// the hue of any pixel whose weighted hues cancel out.  HueConvolve panics if
+// len(kernel) is not kw*kh.
+func (p *NHSVAF64) HueConvolve(kernel []float64, kw, kh int) {
+	if len(kernel) != kw*kh {
+		panic("hsvimage: HueConvolve kernel size does not match its dimensions")
+	}
+	w, h := p.Rect.Dx(), p.Rect.Dy()
+	if w <= 0 || h <= 0 {
+		return
+	}
+
+	// Take a snapshot of the original hues and saturations.
+	hues := make([]float64, w*h)
+	sats := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		i := p.PixOffset(p.Rect.Min.X, p.Rect.Min.Y+y)
+		for x := 0; x < w; x, i = x+1, i+4 {
+			hues[y*w+x] = p.Pix[i]
+			sats[y*w+x] = p.Pix[i+1]
+		}
+	}
+
+	// Convolve.
+	cx, cy := kw/2, kh/2
+	for y := 0; y < h; y++ {
+		i := p.PixOffset(p.Rect.Min.X, p.Rect.Min.Y+y)
+		for x := 0; x < w; x, i = x+1, i+4 {
+			var hs hueSum
+			for ky := 0; ky < kh; ky++ {
+				ny := clampInt(y+ky-cy, 0, h-1)
+				for kx := 0; kx < kw; kx++ {
+					nx := clampInt(x+kx-cx, 0, w-1)
+					j := ny*w + nx
+					hs.add(hues[j], kernel[ky*kw+kx]*sats[j])
+				}
+			}
+			if hs.x != 0.0 || hs.y != 0.0 {
+				p.Pix[i] = hs.mean()
+			}
+		}
+	}
+}
+
+// Deblock returns a copy of an image with value discontinuities at block
+// boundaries smoothed away.  Block boundaries lie every blockSize pixels from
+// the image's minimum corner.  Across each boundary, the step between the two
+// pixels adjacent to the seam is spread into a ramp over the two pixels on
+// either side, with strength, in [0, 1], scaling the amount of smoothing.
+// Pixels not adjacent to a seam, and hence edges within blocks, are left
+// untouched, as are all hues, saturations, and alphas.
+func (p *NHSVA) Deblock(blockSize int, strength float64) *NHSVA {
+	// Copy the image's value channel.
+	w, h := p.Rect.Dx(), p.Rect.Dy()
+	dst := NewNHSVA(p.Rect)
+	vals := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		i := p.PixOffset(p.Rect.Min.X, p.Rect.Min.Y+y)
+		copy(dst.Pix[y*dst.Stride:], p.Pix[i:i+w*4])
+		for x := 0; x < w; x, i = x+1, i+4 {
+			vals[y*w+x] = float64(p.Pix[i+2])
+		}
+	}
+	if blockSize < 2 {
+		return dst
+	}
+
+	// smoothSeam smooths the seam between indexes q0 and q0-step.
+	smoothSeam := func(q0, step int, hasP1, hasQ1 bool) {
+		p0 := q0 - step
+		d := (vals[q0] - vals[p0]) * strength
+		vals[p0] += d / 3.0
+		vals[q0] -= d / 3.0
+		if hasP1 {
+			vals[p0-step] += d / 6.0
+		}
+		if hasQ1 {
+			vals[q0+step] -= d / 6.0
+		}
+	}
+
+	// Smooth vertical seams then horizontal seams.
+	for y := 0; y < h; y++ {
+		for x := blockSize; x < w; x += blockSize {
+			smoothSeam(y*w+x, 1, x >= 2, x+1 < w)
+		}
+	}
+	for y := blockSize; y < h; y += blockSize {
+		for x := 0; x < w; x++ {
+			smoothSeam(y*w+x, w, y >= 2, y+1 < h)
+		}
+	}
+
+	// Store the smoothed values.
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dst.Pix[y*dst.Stride+x*4+2] = clampUint8(vals[y*w+x])
+		}
+	}
+	return dst
+}
+
+// softThreshold shrinks x toward zero by t, returning zero if |x| <= t.
+func softThreshold(x, t float64) float64 {
+	switch {
+	case x > t:
+		return x - t
+	case x < -t:
+		return x + t
+	default:
+		return 0.0
+	}
+}
+
+// DenoiseValue returns a copy of an image with noise in the value channel
+// reduced.  It applies a single-level, two-dimensional Haar wavelet transform
+// to each 2×2 block of values, soft-thresholds the three detail coefficients
+// by threshold, and reconstructs the values.  Low-amplitude variations are
+// therefore suppressed while strong edges survive.  If the image has an odd
+// width or height, the final column or row is left unfiltered.  Hue,
+// saturation, and alpha are left untouched.
+func (p *NHSVA) DenoiseValue(threshold uint8) *NHSVA {
+	w, h := p.Rect.Dx(), p.Rect.Dy()
+	dst := NewNHSVA(p.Rect)
+	for y := 0; y < h; y++ {
+		i := p.PixOffset(p.Rect.Min.X, p.Rect.Min.Y+y)
+		copy(dst.Pix[y*dst.Stride:], p.Pix[i:i+w*4])
+	}
+	t := float64(threshold)
+	for y := 0; y+1 < h; y += 2 {
+		for x := 0; x+1 < w; x += 2 {
+			// Perform the forward transform.
+			i00 := y*dst.Stride + x*4 + 2
+			i01 := i00 + 4
+			i10 := i00 + dst.Stride
+			i11 := i10 + 4
+			p00, p01 := float64(dst.Pix[i00]), float64(dst.Pix[i01])
+			p10, p11 := float64(dst.Pix[i10]), float64(dst.Pix[i11])
+			avg := (p00 + p01 + p10 + p11) / 4.0
+			dh := (p00 - p01 + p10 - p11) / 4.0
+			dv := (p00 + p01 - p10 - p11) / 4.0
+			dd := (p00 - p01 - p10 + p11) / 4.0
+
+			// Threshold the details.
+			dh = softThreshold(dh, t)
+			dv = softThreshold(dv, t)
+			dd = softThreshold(dd, t)
+
+			// Perform the inverse transform.
+			dst.Pix[i00] = clampUint8(avg + dh + dv + dd)
+			dst.Pix[i01] = clampUint8(avg - dh + dv - dd)
+			dst.Pix[i10] = clampUint8(avg + dh - dv - dd)
+			dst.Pix[i11] = clampUint8(avg - dh - dv + dd)
+		}
+	}
+	return dst
+}
+
+// BlurValue blurs an image's value channel in place with a box filter of the
+// given radius, softening brightness without shifting hues or reducing
+// saturation.  Hue, saturation, and alpha are left untouched.  Samples beyond
+// the image's edges are replaced by the nearest edge sample.
+func (p *NHSVAF64) BlurValue(radius int) {
+	w, h := p.Rect.Dx(), p.Rect.Dy()
+	if radius <= 0 || w <= 0 || h <= 0 {
+		return
+	}
+	vals := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		i := p.PixOffset(p.Rect.Min.X, p.Rect.Min.Y+y)
+		for x := 0; x < w; x, i = x+1, i+4 {
+			vals[y*w+x] = p.Pix[i+2]
+		}
+	}
+	vals = boxBlur(vals, w, h, radius)
+	for y := 0; y < h; y++ {
+		i := p.PixOffset(p.Rect.Min.X, p.Rect.Min.Y+y)
+		for x := 0; x < w; x, i = x+1, i+4 {
+			p.Pix[i+2] = vals[y*w+x]
+		}
+	}
+}
+
+// SobelValue applies the Sobel operator to an image's value channel and
+// returns the gradient magnitude as a grayscale image with the same bounds.
+// Magnitudes are scaled so that a sharp step from a value of 0 to a value of
+// 1 maps to 255; steeper diagonal gradients saturate at 255.  Samples beyond
+// the image's edges are replaced by the nearest edge sample.  Hue and
+// saturation are ignored.
+func SobelValue(src *NHSVAF64) *image.Gray {
+	r := src.Rect
+	dst := image.NewGray(r)
+	valAt := func(x, y int) float64 {
+		x = clampInt(x, r.Min.X, r.Max.X-1)
+		y = clampInt(y, r.Min.Y, r.Max.Y-1)
+		return src.Pix[src.PixOffset(x, y)+2]
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		j := dst.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x, j = x+1, j+1 {
+			nw, n, ne := valAt(x-1, y-1), valAt(x, y-1), valAt(x+1, y-1)
+			w, e := valAt(x-1, y), valAt(x+1, y)
+			sw, s, se := valAt(x-1, y+1), valAt(x, y+1), valAt(x+1, y+1)
+			gx := (ne + 2.0*e + se) - (nw + 2.0*w + sw)
+			gy := (sw + 2.0*s + se) - (nw + 2.0*n + ne)
+			dst.Pix[j] = clampUint8(math.Hypot(gx, gy) * 255.0 / 4.0)
+		}
+	}
+	return dst
+}
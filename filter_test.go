@@ -0,0 +1,268 @@
+// This file tests neighborhood filters for HSV images.
+
+package hsvimage
+
+import (
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+	"math"
+	"testing"
+)
+
+// valueStats returns the mean and variance of an NHSVA image's value channel.
+func valueStats(img *NHSVA) (mean, variance float64) {
+	var sum, sum2 float64
+	n := 0
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			v := float64(img.NHSVAAt(x, y).V)
+			sum += v
+			sum2 += v * v
+			n++
+		}
+	}
+	mean = sum / float64(n)
+	return mean, sum2/float64(n) - mean*mean
+}
+
+// TestOrtonEffect confirms that the Orton effect brightens and softens an
+// image without altering hue, saturation, or alpha.
+func TestOrtonEffect(t *testing.T) {
+	img := NewNHSVA(image.Rect(2, 3, 12, 13))
+	for y := 3; y < 13; y++ {
+		for x := 2; x < 12; x++ {
+			v := uint8(50)
+			if (x+y)%2 == 0 {
+				v = 200
+			}
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: uint8(x * 20), S: 180, V: v, A: 255})
+		}
+	}
+	glow := img.OrtonEffect(2, 0.75)
+	if !glow.Rect.Eq(img.Rect) {
+		t.Fatalf("Expected bounds %v but saw %v", img.Rect, glow.Rect)
+	}
+	m0, v0 := valueStats(img)
+	m1, v1 := valueStats(glow)
+	if m1 <= m0 {
+		t.Fatalf("Expected mean value to increase from %.2f but saw %.2f", m0, m1)
+	}
+	if v1 >= v0 {
+		t.Fatalf("Expected value variance to decrease from %.2f but saw %.2f", v0, v1)
+	}
+	for y := 3; y < 13; y++ {
+		for x := 2; x < 12; x++ {
+			c0, c1 := img.NHSVAAt(x, y), glow.NHSVAAt(x, y)
+			if c0.H != c1.H || c0.S != c1.S || c0.A != c1.A {
+				t.Fatalf("Expected %v to retain its hue, saturation, and alpha but saw %v at (%d, %d)", c0, c1, x, y)
+			}
+		}
+	}
+}
+
+// TestHueConvolve confirms that convolving with a uniform 3×3 kernel matches
+// a saturation-weighted circular mean of each 3×3 neighborhood.
+func TestHueConvolve(t *testing.T) {
+	const wd, ht = 7, 5
+	img := NewNHSVAF64(image.Rect(10, 20, 10+wd, 20+ht))
+	orig := make([]hsvcolor.NHSVAF64, wd*ht)
+	for y := 0; y < ht; y++ {
+		for x := 0; x < wd; x++ {
+			c := hsvcolor.NHSVAF64{
+				H: math.Mod(330.0+float64(x*x*11+y*17), 360.0),
+				S: float64((x+y)%4) / 3.0,
+				V: 0.75,
+				A: 1.0,
+			}
+			orig[y*wd+x] = c
+			img.SetNHSVAF64(10+x, 20+y, c)
+		}
+	}
+	box := []float64{1, 1, 1, 1, 1, 1, 1, 1, 1}
+	img.HueConvolve(box, 3, 3)
+	for y := 0; y < ht; y++ {
+		for x := 0; x < wd; x++ {
+			// Compute the expected hue.
+			var sx, sy float64
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					c := orig[clampInt(y+dy, 0, ht-1)*wd+clampInt(x+dx, 0, wd-1)]
+					hr := c.H * math.Pi / 180.0
+					sx += c.S * math.Cos(hr)
+					sy += c.S * math.Sin(hr)
+				}
+			}
+			want := orig[y*wd+x]
+			if sx != 0.0 || sy != 0.0 {
+				want.H = math.Mod(math.Atan2(sy, sx)*180.0/math.Pi+360.0, 360.0)
+			}
+
+			// Compare it to the actual hue.
+			c := img.NHSVAF64At(10+x, 20+y)
+			if hueDistance(c.H, want.H) > 1e-9 || c.S != want.S || c.V != want.V || c.A != want.A {
+				t.Fatalf("Expected %v but saw %v at (%d, %d)", want, c, x, y)
+			}
+		}
+	}
+}
+
+// TestDeblock confirms that deblocking reduces value steps at block
+// boundaries but preserves edges within blocks.
+func TestDeblock(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			v := uint8(80 + (x/8)*20 + (y/8)*20)
+			if x%8 >= 4 {
+				v += 50 // A genuine edge in the middle of each block
+			}
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: 200, S: 150, V: v, A: 255})
+		}
+	}
+	db := img.Deblock(8, 1.0)
+	before, after := img.BlockinessScore(8), db.BlockinessScore(8)
+	if after >= before {
+		t.Fatalf("Expected blockiness to decrease from %.3f but saw %.3f", before, after)
+	}
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			c0, c1 := img.NHSVAAt(x, y), db.NHSVAAt(x, y)
+			if c0.H != c1.H || c0.S != c1.S || c0.A != c1.A {
+				t.Fatalf("Expected only value to change from %v but saw %v at (%d, %d)", c0, c1, x, y)
+			}
+			step0 := int(img.NHSVAAt(4, y).V) - int(img.NHSVAAt(3, y).V)
+			step1 := int(db.NHSVAAt(4, y).V) - int(db.NHSVAAt(3, y).V)
+			if step0 != step1 {
+				t.Fatalf("Expected the interior edge in row %d to remain %d but saw %d", y, step0, step1)
+			}
+		}
+	}
+	for y := 0; y < 32; y++ {
+		step0 := int(img.NHSVAAt(8, y).V) - int(img.NHSVAAt(7, y).V)
+		step1 := int(db.NHSVAAt(8, y).V) - int(db.NHSVAAt(7, y).V)
+		if abs(step1) >= abs(step0) {
+			t.Fatalf("Expected the seam step in row %d to shrink from %d but saw %d", y, step0, step1)
+		}
+	}
+}
+
+// abs returns the absolute value of an int.
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// TestDenoiseValue confirms that wavelet denoising suppresses low-amplitude
+// value noise while preserving strong edges.
+func TestDenoiseValue(t *testing.T) {
+	// Draw a noisy image with a strong vertical edge.
+	img := NewNHSVA(image.Rect(1, 1, 17, 17))
+	noise := []int{2, -3, 1, 0, -2, 3, -1, 2, 0}
+	for y := 1; y < 17; y++ {
+		for x := 1; x < 17; x++ {
+			v := 60
+			if x >= 9 {
+				v = 200
+			}
+			v += noise[(x*5+y*3)%len(noise)]
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: 100, S: 80, V: uint8(v), A: 255})
+		}
+	}
+
+	// Denoise the image and check the result.
+	dn := img.DenoiseValue(4)
+	_, v0 := valueStats(img.SubImage(image.Rect(1, 1, 9, 17)).(*NHSVA))
+	_, v1 := valueStats(dn.SubImage(image.Rect(1, 1, 9, 17)).(*NHSVA))
+	if v1 >= v0/4.0 {
+		t.Fatalf("Expected noise variance to drop well below %.3f but saw %.3f", v0, v1)
+	}
+	for y := 1; y < 17; y++ {
+		left, right := dn.NHSVAAt(8, y).V, dn.NHSVAAt(9, y).V
+		if int(right)-int(left) < 130 {
+			t.Fatalf("Expected a strong edge to survive in row %d but saw %d to %d", y, left, right)
+		}
+		for x := 1; x < 17; x++ {
+			c0, c1 := img.NHSVAAt(x, y), dn.NHSVAAt(x, y)
+			if c0.H != c1.H || c0.S != c1.S || c0.A != c1.A {
+				t.Fatalf("Expected only value to change from %v but saw %v at (%d, %d)", c0, c1, x, y)
+			}
+		}
+	}
+}
+
+// TestBlurValue confirms that BlurValue spreads a bright pixel's value to its
+// neighbors, conserves total value, and leaves other channels alone.
+func TestBlurValue(t *testing.T) {
+	img := NewNHSVAF64(image.Rect(0, 0, 11, 9))
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 11; x++ {
+			img.SetNHSVAF64(x, y, hsvcolor.NHSVAF64{H: float64(x * 30), S: 0.5, V: 0.0, A: 0.75})
+		}
+	}
+	img.SetNHSVAF64(5, 4, hsvcolor.NHSVAF64{H: 150.0, S: 0.5, V: 1.0, A: 0.75})
+	img.BlurValue(1)
+	var total float64
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 11; x++ {
+			c := img.NHSVAF64At(x, y)
+			if c.H != float64(x*30) || c.S != 0.5 || c.A != 0.75 {
+				t.Fatalf("Expected only value to change but saw %v at (%d, %d)", c, x, y)
+			}
+			exp := 0.0
+			if abs(x-5) <= 1 && abs(y-4) <= 1 {
+				exp = 1.0 / 9.0
+			}
+			if math.Abs(c.V-exp) > 1e-12 {
+				t.Fatalf("Expected value %.5f but saw %.5f at (%d, %d)", exp, c.V, x, y)
+			}
+			total += c.V
+		}
+	}
+	if math.Abs(total-1.0) > 1e-12 {
+		t.Fatalf("Expected a total value of 1 but saw %.5f", total)
+	}
+
+	// A uniform image should be unaffected, even at the edges.
+	flat := NewNHSVAF64(image.Rect(0, 0, 5, 3))
+	for i := 2; i < len(flat.Pix); i += 4 {
+		flat.Pix[i] = 0.4
+	}
+	flat.BlurValue(4)
+	for i := 2; i < len(flat.Pix); i += 4 {
+		if math.Abs(flat.Pix[i]-0.4) > 1e-12 {
+			t.Fatalf("Expected a uniform value of 0.4 but saw %.5f", flat.Pix[i])
+		}
+	}
+}
+
+// TestSobelValue confirms that SobelValue highlights a sharp vertical step in
+// value while leaving flat regions dark, regardless of hue.
+func TestSobelValue(t *testing.T) {
+	img := NewNHSVAF64(image.Rect(2, 3, 10, 9))
+	for y := 3; y < 9; y++ {
+		for x := 2; x < 10; x++ {
+			c := hsvcolor.NHSVAF64{H: float64(x * 40), S: 1.0, V: 0.0, A: 1.0}
+			if x >= 6 {
+				c.V = 1.0
+			}
+			img.SetNHSVAF64(x, y, c)
+		}
+	}
+	edges := SobelValue(img)
+	if !edges.Rect.Eq(img.Rect) {
+		t.Fatalf("Expected bounds %v but saw %v", img.Rect, edges.Rect)
+	}
+	for y := 3; y < 9; y++ {
+		for x := 2; x < 10; x++ {
+			exp := uint8(0)
+			if x == 5 || x == 6 {
+				exp = 255
+			}
+			if g := edges.GrayAt(x, y).Y; g != exp {
+				t.Fatalf("Expected %d but saw %d at (%d, %d)", exp, g, x, y)
+			}
+		}
+	}
+}
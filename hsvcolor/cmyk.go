@@ -0,0 +1,25 @@
+package hsvcolor
+
+import "image/color"
+
+// NHSVAToCMYK converts an NHSVA color to a color.CMYK color by way of
+// non-alpha-premultiplied RGB.  Because CMYK has no alpha channel, c's alpha
+// is ignored.  A color with zero value maps to pure black ink (K=255) with no
+// cyan, magenta, or yellow, regardless of its hue and saturation.
+func NHSVAToCMYK(c NHSVA) color.CMYK {
+	if c.V == 0 {
+		return color.CMYK{C: 0, M: 0, Y: 0, K: 255}
+	}
+	r, g, b, _ := NHSVA{H: c.H, S: c.S, V: c.V, A: 255}.RGBA()
+	cc, mm, yy, kk := color.RGBToCMYK(scale16To8(uint16(r)), scale16To8(uint16(g)), scale16To8(uint16(b)))
+	return color.CMYK{C: cc, M: mm, Y: yy, K: kk}
+}
+
+// CMYKToNHSVA converts a color.CMYK color to an opaque NHSVA color by way of
+// RGB.  Pure black ink (K=255) maps to black regardless of the other inks.
+func CMYKToNHSVA(c color.CMYK) NHSVA {
+	if c.K == 255 {
+		return NHSVA{H: 0, S: 0, V: 0, A: 255}
+	}
+	return NHSVAModel.Convert(c).(NHSVA)
+}
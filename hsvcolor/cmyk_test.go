@@ -0,0 +1,49 @@
+// This file tests conversions between HSV colors and CMYK colors.
+
+package hsvcolor
+
+import (
+	"image/color"
+	"testing"
+)
+
+// TestNHSVAToCMYK confirms that NHSVAToCMYK agrees with the standard library's
+// RGB-to-CMYK conversion.
+func TestNHSVAToCMYK(t *testing.T) {
+	for _, cEq := range colorEquivalences {
+		hsv := NHSVA{cEq.HSV[0], cEq.HSV[1], cEq.HSV[2], 255}
+		cmyk := NHSVAToCMYK(hsv)
+		c, m, y, k := color.RGBToCMYK(cEq.RGB[0], cEq.RGB[1], cEq.RGB[2])
+		if !near(cmyk.C, c) || !near(cmyk.M, m) || !near(cmyk.Y, y) || !near(cmyk.K, k) {
+			t.Fatalf("Incorrectly mapped %s from %v to %v (expected %v)", cEq.Name, hsv, cmyk, color.CMYK{c, m, y, k})
+		}
+	}
+
+	// Black should use only black ink, whatever its hue and saturation.
+	for _, hsv := range []NHSVA{{0, 0, 0, 255}, {100, 200, 0, 255}, {200, 255, 0, 0}} {
+		if cmyk := NHSVAToCMYK(hsv); cmyk != (color.CMYK{0, 0, 0, 255}) {
+			t.Fatalf("Expected %v to map to pure black ink but saw %v", hsv, cmyk)
+		}
+	}
+}
+
+// TestCMYKToNHSVA confirms that CMYKToNHSVA agrees with the standard library's
+// CMYK-to-RGB conversion.
+func TestCMYKToNHSVA(t *testing.T) {
+	for _, cEq := range colorEquivalences {
+		c, m, y, k := color.RGBToCMYK(cEq.RGB[0], cEq.RGB[1], cEq.RGB[2])
+		cmyk := color.CMYK{c, m, y, k}
+		hsv := CMYKToNHSVA(cmyk)
+		r, g, b := color.CMYKToRGB(c, m, y, k)
+		exp := NHSVAModel.Convert(color.RGBA{r, g, b, 255}).(NHSVA)
+		if !near(hsv.H, exp.H) || !near(hsv.S, exp.S) || !near(hsv.V, exp.V) || hsv.A != 255 {
+			t.Fatalf("Incorrectly mapped %s from %v to %v (expected %v)", cEq.Name, cmyk, hsv, exp)
+		}
+		if !near(hsv.V, cEq.HSV[2]) {
+			t.Fatalf("Incorrectly mapped %s from %v to %v (expected value %d)", cEq.Name, cmyk, hsv, cEq.HSV[2])
+		}
+	}
+	if hsv := CMYKToNHSVA(color.CMYK{100, 50, 25, 255}); hsv != (NHSVA{0, 0, 0, 255}) {
+		t.Fatalf("Expected pure black ink to map to black but saw %v", hsv)
+	}
+}
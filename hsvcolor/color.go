@@ -30,8 +30,8 @@ func max3uint32(a, b, c uint32) uint32 {
 	return m
 }
 
-// nhsvaFloat64ToRGBA is a helper function for NHSVA.RGBA and NHSVA64.RGBA that
-// converts float64 versions of H, S, V, and A to RGBA.
+// nhsvaFloat64ToRGBA is a helper function for NHSVA64.RGBA and NHSVAF64.RGBA
+// that converts float64 versions of H, S, V, and A to RGBA.
 func nhsvaFloat64ToRGBA(hf, sf, vf, af float64) (r uint32, g uint32, b uint32, a uint32) {
 	// Follow the textbook formulas for converting HSV to RGB.
 	cf := vf * sf
@@ -61,18 +61,21 @@ func nhsvaFloat64ToRGBA(hf, sf, vf, af float64) (r uint32, g uint32, b uint32, a
 	gf += mf
 	bf += mf
 
-	// Premultiply by alpha then convert from float64 to uint32.
-	r16 := uint32(rf * af * 65535.0)
-	g16 := uint32(gf * af * 65535.0)
-	b16 := uint32(bf * af * 65535.0)
-	a16 := uint32(af * 65535.0)
+	// Premultiply by alpha then convert from float64 to uint32, rounding to
+	// the nearest integer to agree with the grayscale fast paths.
+	r16 := uint32(rf*af*65535.0 + 0.5)
+	g16 := uint32(gf*af*65535.0 + 0.5)
+	b16 := uint32(bf*af*65535.0 + 0.5)
+	a16 := uint32(af*65535.0 + 0.5)
 	return r16, g16, b16, a16
 }
 
 // NHSVA represents a non-alpha-premultiplied 32-bit HSV color.  Note that all
 // color channels range from 0 to 255.  (It is more common for hue to range
 // from 0 to 359 and saturation and value to range from 0 to 1, but that's not
-// what we do here.)
+// what we do here.)  A color with zero saturation is a gray, whose hue is
+// undefined; by convention, such colors are given a hue of 0, which should be
+// ignored (see HueDefined).
 type NHSVA struct {
 	H, S, V, A uint8
 }
@@ -85,16 +88,7 @@ func nhsvaModel(c color.Color) color.Color {
 	}
 
 	// Produce a 64-bit color then scale it down to 32 bits.
-	nhsva64 := nhsva64Model(c).(NHSVA64)
-	scale := func(n16 uint16) uint8 {
-		return uint8((uint32(n16)*255 + 32768) / 65535)
-	}
-	return NHSVA{
-		H: scale(nhsva64.H),
-		S: scale(nhsva64.S),
-		V: scale(nhsva64.V),
-		A: scale(nhsva64.A),
-	}
+	return nhsva64Model(c).(NHSVA64).To8()
 }
 
 // NHSVAModel is a color model for NHSVA (non-alpha-premultiplied hue,
@@ -113,13 +107,104 @@ func (c NHSVA) RGBA() (r, g, b, a uint32) {
 		return v16pm, v16pm, v16pm, a16
 	}
 
-	// We work with float64 values primarily out of laziness: most of the
-	// conversion formulas on the Web assume real values.
-	hf := float64(c.H) * 360.0 / 255.0
-	sf := float64(c.S) / 255.0
-	vf := float64(c.V) / 255.0
-	af := float64(c.A) / 255.0
-	return nhsvaFloat64ToRGBA(hf, sf, vf, af)
+	// Follow the same textbook formulas as nhsvaFloat64ToRGBA but in fixed
+	// point.  Hue is split into a sector, k, and a position within that
+	// sector, f, in units of 1/255.  Chroma, the intermediate component, and
+	// the offset are then all exact multiples of 1/255³.
+	h6 := uint64(c.H) * 6
+	k, f := h6/255, h6%255
+	if k == 6 {
+		k, f = 5, 255 // A hue of 255 (360°) lies at the end of the last sector.
+	}
+	vs := uint64(c.V) * uint64(c.S)
+	cx := vs * 255 // Chroma
+	xx := vs * f   // Intermediate component
+	if k%2 == 1 {
+		xx = vs * (255 - f)
+	}
+	mx := uint64(c.V)*255*255 - cx // Offset
+	var rx, gx, bx uint64
+	switch k {
+	case 0:
+		rx, gx, bx = cx, xx, 0
+	case 1:
+		rx, gx, bx = xx, cx, 0
+	case 2:
+		rx, gx, bx = 0, cx, xx
+	case 3:
+		rx, gx, bx = 0, xx, cx
+	case 4:
+		rx, gx, bx = xx, 0, cx
+	default:
+		rx, gx, bx = cx, 0, xx
+	}
+
+	// Premultiply by alpha and scale from units of 1/255⁴ to 16 bits,
+	// rounding to the nearest integer.
+	const denom = 255 * 255 * 255 * 255
+	scale := func(n uint64) uint32 {
+		return uint32(((n+mx)*uint64(c.A)*65535 + denom/2) / denom)
+	}
+	return scale(rx), scale(gx), scale(bx), a16
+}
+
+// NHSVAFromDegrees returns an NHSVA color with the given saturation, value,
+// and alpha and a hue given in conventional degrees rather than NHSVA's
+// 0–255 scale.  hDeg is first wrapped into [0, 360) then scaled and rounded to
+// the nearest representable hue.
+func NHSVAFromDegrees(hDeg float64, s, v, a uint8) NHSVA {
+	h := math.Round(wrapDegrees(hDeg) * 255.0 / 360.0)
+	if h >= 255.0 {
+		h = 0.0 // 255 and 0 both represent red.
+	}
+	return NHSVA{H: uint8(h), S: s, V: v, A: a}
+}
+
+// HueDegrees returns an NHSVA color's hue in conventional degrees, in
+// [0, 360].
+func (c NHSVA) HueDegrees() float64 {
+	return float64(c.H) * 360.0 / 255.0
+}
+
+// HueDefined reports whether an NHSVA color's hue is meaningful.  It returns
+// false for grays (colors with zero saturation), whose hue is stored as 0 by
+// convention and should not contribute to hue averages or interpolations.
+func (c NHSVA) HueDefined() bool {
+	return c.S != 0
+}
+
+// Analogous returns an analogous color scheme of count colors whose hues are
+// spaced spread apart around the color wheel, wrapping as necessary, and
+// centered on c's hue.  Saturation, value, and alpha are copied from c.  When
+// count is even, c itself is not included; instead, the two middle colors lie
+// spread/2 on either side of it (rounded toward c).  Analogous returns nil if
+// count is not positive.
+func (c NHSVA) Analogous(count int, spread uint8) []NHSVA {
+	if count <= 0 {
+		return nil
+	}
+	scheme := make([]NHSVA, count)
+	for k := range scheme {
+		off2 := (2*k - (count - 1)) * int(spread) // Twice the hue offset
+		h := (int(c.H) + off2/2) % 255            // 255 and 0 both represent red.
+		if h < 0 {
+			h += 255
+		}
+		scheme[k] = c
+		scheme[k].H = uint8(h)
+	}
+	return scheme
+}
+
+// PerceivedLuma returns the luma of an NHSVA color, computed from its RGB
+// equivalent using the Rec. 709 weights.  Unlike value, luma accounts for the
+// eye's greater sensitivity to green than to red and to red than to blue, so,
+// for example, yellow has a greater luma than blue at the same value.  Alpha
+// is ignored.
+func (c NHSVA) PerceivedLuma() uint8 {
+	r, g, b, _ := NHSVA{H: c.H, S: c.S, V: c.V, A: 255}.RGBA()
+	y := 0.2126*float64(r) + 0.7152*float64(g) + 0.0722*float64(b)
+	return uint8(math.Min(y*255.0/65535.0+0.5, 255.0))
 }
 
 // NHSVA64 represents a non-alpha-premultiplied 64-bit HSV color.  Note that
@@ -156,7 +241,11 @@ func nhsva64Model(c color.Color) color.Color {
 		s = (65535 * delta) / cMax
 	}
 
-	// Compute hue.
+	// Compute hue.  When two channels tie for the maximum, the first of
+	// red, green, and blue determines the formula.  All applicable formulas
+	// agree in that case (yellow, cyan, and magenta for red=green,
+	// green=blue, and red=blue, respectively), so the choice affects only
+	// the determinism of the computation, not its result.
 	if delta == 0 {
 		return NHSVA64{0, 0, uint16(v), uint16(a)} // Gray + alpha
 	}
@@ -178,7 +267,9 @@ func nhsva64Model(c color.Color) color.Color {
 }
 
 // NHSVA64Model is a color model for NHSVA64 (non-alpha-premultiplied hue,
-// saturation, and value plus alpha) colors.
+// saturation, and value plus alpha) colors.  Conversion is deterministic even
+// when two RGB channels tie for the maximum: the tied channels yield the same
+// hue (60°, 180°, or 300°) as every other textbook formulation.
 var NHSVA64Model color.Model = color.ModelFunc(nhsva64Model)
 
 // RGBA converts an NHSVA64 color to alpha-premultiplied RGBA.
@@ -238,7 +329,8 @@ func nhsvaF64Model(c color.Color) color.Color {
 		sf = delta / cMax
 	}
 
-	// Compute hue.
+	// Compute hue.  As in nhsva64Model, ties for the maximum channel are
+	// broken in favor of red, then green, then blue.
 	if delta == 0.0 {
 		return NHSVAF64{0.0, 0.0, vf, af} // Gray + alpha
 	}
@@ -275,10 +367,250 @@ func (c NHSVAF64) RGBA() (r, g, b, a uint32) {
 
 	// Handle the easy case: a grayscale value.
 	if sf == 0.0 {
-		v16pm := uint32(vf * af * 65535.0)
-		return v16pm, v16pm, v16pm, uint32(af * 65535.0)
+		v16pm := uint32(vf*af*65535.0 + 0.5)
+		return v16pm, v16pm, v16pm, uint32(af*65535.0 + 0.5)
 	}
 
 	// Handle all other cases.
 	return nhsvaFloat64ToRGBA(hf, sf, vf, af)
 }
+
+// HueDefined reports whether an NHSVAF64 color's hue is meaningful.  It
+// returns false for grays (colors with zero saturation).
+func (c NHSVAF64) HueDefined() bool {
+	return c.S != 0.0
+}
+
+// NHSVAF64FromHSV returns an NHSVAF64 color with the given hue, in degrees,
+// and saturation, value, and alpha, each in [0, 1].  These are the same
+// conventions used by the HSV functions of the popular
+// github.com/lucasb-eyer/go-colorful package.  Hue is wrapped into [0, 360),
+// and the remaining channels are clamped to [0, 1], so the result always
+// satisfies InGamut.
+func NHSVAF64FromHSV(h, s, v, a float64) NHSVAF64 {
+	h = wrapDegrees(h)
+	clamp01 := func(x float64) float64 { return math.Max(0.0, math.Min(1.0, x)) }
+	return NHSVAF64{H: h, S: clamp01(s), V: clamp01(v), A: clamp01(a)}
+}
+
+// ToColorfulHSV returns an NHSVAF64 color's hue, in degrees, and its
+// saturation and value, each in [0, 1], in the form expected by the HSV
+// functions of the github.com/lucasb-eyer/go-colorful package.  Because
+// NHSVAF64 already follows those conventions, the channels are returned as
+// is.  Alpha, which go-colorful does not represent, is dropped.
+func (c NHSVAF64) ToColorfulHSV() (h, s, v float64) {
+	return c.H, c.S, c.V
+}
+
+// InGamut reports whether an NHSVAF64 color's channels all lie within their
+// expected ranges: [0, 360) for hue and [0, 1] for everything else.
+func (c NHSVAF64) InGamut() bool {
+	return c.H >= 0.0 && c.H < 360.0 &&
+		c.S >= 0.0 && c.S <= 1.0 &&
+		c.V >= 0.0 && c.V <= 1.0 &&
+		c.A >= 0.0 && c.A <= 1.0
+}
+
+// NHSVAF32 represents a non-alpha-premultiplied HSV color with each channel
+// represented by a 32-bit floating-point number.  As with NHSVAF64, hue is a
+// value in [0, 360); and the remaining channels are values in [0, 1].
+type NHSVAF32 struct {
+	H, S, V, A float32
+}
+
+// nhsvaF32Model converts an arbitrary color to an NHSVAF32 color.
+func nhsvaF32Model(c color.Color) color.Color {
+	// Handle the easy case first: already NHSVAF32.
+	if _, ok := c.(NHSVAF32); ok {
+		return c
+	}
+
+	// Produce an NHSVAF64 color then narrow each channel.
+	f64 := nhsvaF64Model(c).(NHSVAF64)
+	return NHSVAF32{
+		H: float32(f64.H),
+		S: float32(f64.S),
+		V: float32(f64.V),
+		A: float32(f64.A),
+	}
+}
+
+// NHSVAF32Model is a color model for NHSVAF32 (non-alpha-premultiplied hue,
+// saturation, and value plus alpha, with 32-bit floating-point channels)
+// colors.
+var NHSVAF32Model color.Model = color.ModelFunc(nhsvaF32Model)
+
+// RGBA converts an NHSVAF32 color to alpha-premultiplied RGBA.
+func (c NHSVAF32) RGBA() (r, g, b, a uint32) {
+	return NHSVAF64{
+		H: float64(c.H),
+		S: float64(c.S),
+		V: float64(c.V),
+		A: float64(c.A),
+	}.RGBA()
+}
+
+// HueDistance returns the shortest angular distance, in degrees, between the
+// hues of two NHSVA colors.  The result lies in [0, 180].
+func (c NHSVA) HueDistance(other NHSVA) float64 {
+	d := math.Abs(float64(c.H)-float64(other.H)) * 360.0 / 255.0
+	if d > 180.0 {
+		d = 360.0 - d
+	}
+	return d
+}
+
+// NearestNHSVA returns the index of the palette color nearest to the target
+// color or -1 if the palette is empty.  Distance is measured by combining the
+// hue, saturation, and value differences, each normalized to [0, 1].  Hue
+// differences are weighted by the lesser of the two saturations because hue is
+// less meaningful as a color approaches gray.
+func NearestNHSVA(target NHSVA, palette []NHSVA) int {
+	best, bestDist := -1, math.Inf(1)
+	for i, c := range palette {
+		sMin := math.Min(float64(target.S), float64(c.S)) / 255.0
+		dh := sMin * target.HueDistance(c) / 180.0
+		ds := (float64(target.S) - float64(c.S)) / 255.0
+		dv := (float64(target.V) - float64(c.V)) / 255.0
+		dist := dh*dh + ds*ds + dv*dv
+		if dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+// scale16To8 scales a 16-bit channel to an 8-bit channel, rounding to the
+// nearest value.
+func scale16To8(n16 uint16) uint8 {
+	return uint8((uint32(n16)*255 + 32768) / 65535)
+}
+
+// scaleHue16To8 scales a 16-bit hue to an 8-bit hue, rounding to the nearest
+// value.  Because both 0 and 255 represent red in the 8-bit scale, hues that
+// round up to 255 are wrapped to 0 so that hues on either side of red are
+// represented consistently with red itself.
+func scaleHue16To8(h16 uint16) uint8 {
+	h8 := scale16To8(h16)
+	if h8 == 255 {
+		h8 = 0
+	}
+	return h8
+}
+
+// scaleF64To16 scales a [0, 1] floating-point channel to a 16-bit channel,
+// clamping and rounding to the nearest value.
+func scaleF64To16(f float64) uint16 {
+	return uint16(math.Round(math.Max(0.0, math.Min(1.0, f)) * 65535.0))
+}
+
+// wrapDegrees reduces an angle in degrees to the range [0, 360).
+func wrapDegrees(h float64) float64 {
+	h = math.Mod(h, 360.0)
+	if h < 0.0 {
+		h += 360.0
+	}
+	if h >= 360.0 {
+		h = 0.0 // Adding 360 to -ε rounds to 360.
+	}
+	return h
+}
+
+// hueF64To16 scales a hue in degrees to a 16-bit hue, wrapping hues outside
+// [0, 360] around the color wheel.  (360 itself is preserved so that an
+// integral hue of 65535 survives a round trip through NHSVAF64.)
+func hueF64To16(h float64) uint16 {
+	if h < 0.0 || h > 360.0 {
+		h = wrapDegrees(h)
+	}
+	return uint16(math.Round(h * 65535.0 / 360.0))
+}
+
+// To64 converts an NHSVA color to an NHSVA64 color by scaling each channel
+// directly, without an intermediate conversion to RGB.
+func (c NHSVA) To64() NHSVA64 {
+	return NHSVA64{
+		H: uint16(c.H) * 257,
+		S: uint16(c.S) * 257,
+		V: uint16(c.V) * 257,
+		A: uint16(c.A) * 257,
+	}
+}
+
+// ToF64 converts an NHSVA color to an NHSVAF64 color by scaling each channel
+// directly, without an intermediate conversion to RGB.
+func (c NHSVA) ToF64() NHSVAF64 {
+	return c.To64().ToF64()
+}
+
+// To8 converts an NHSVA64 color to an NHSVA color by scaling each channel
+// directly, without an intermediate conversion to RGB.  Hues that round to
+// 255 are represented as 0, which denotes the same red.
+func (c NHSVA64) To8() NHSVA {
+	return NHSVA{
+		H: scaleHue16To8(c.H),
+		S: scale16To8(c.S),
+		V: scale16To8(c.V),
+		A: scale16To8(c.A),
+	}
+}
+
+// ToF64 converts an NHSVA64 color to an NHSVAF64 color by scaling each channel
+// directly, without an intermediate conversion to RGB.
+func (c NHSVA64) ToF64() NHSVAF64 {
+	return NHSVAF64{
+		H: float64(c.H) * 360.0 / 65535.0,
+		S: float64(c.S) / 65535.0,
+		V: float64(c.V) / 65535.0,
+		A: float64(c.A) / 65535.0,
+	}
+}
+
+// To8 converts an NHSVAF64 color to an NHSVA color by scaling each channel
+// directly, without an intermediate conversion to RGB.  Out-of-range hues wrap
+// around the color wheel; all other out-of-range channels are clamped.
+func (c NHSVAF64) To8() NHSVA {
+	return c.To64().To8()
+}
+
+// To64 converts an NHSVAF64 color to an NHSVA64 color by scaling each channel
+// directly, without an intermediate conversion to RGB.  Out-of-range hues wrap
+// around the color wheel; all other out-of-range channels are clamped.
+func (c NHSVAF64) To64() NHSVA64 {
+	return NHSVA64{
+		H: hueF64To16(c.H),
+		S: scaleF64To16(c.S),
+		V: scaleF64To16(c.V),
+		A: scaleF64To16(c.A),
+	}
+}
+
+// LerpNHSVAF64 interpolates between two NHSVAF64 colors.  Hue is interpolated
+// along the shorter arc of the color wheel, and saturation, value, and alpha
+// are interpolated linearly.  If only one of the two colors has a defined hue
+// (see HueDefined), the result takes that color's hue so that a gray does not
+// pull the hue toward red.  t is clamped to [0, 1], with 0 producing a and 1
+// producing b (modulo hue wraparound).
+func LerpNHSVAF64(a, b NHSVAF64, t float64) NHSVAF64 {
+	t = math.Max(0.0, math.Min(1.0, t))
+	switch {
+	case a.HueDefined() && !b.HueDefined():
+		b.H = a.H
+	case !a.HueDefined() && b.HueDefined():
+		a.H = b.H
+	}
+	dh := math.Mod(b.H-a.H, 360.0)
+	switch {
+	case dh > 180.0:
+		dh -= 360.0
+	case dh < -180.0:
+		dh += 360.0
+	}
+	h := math.Mod(a.H+t*dh+360.0, 360.0)
+	return NHSVAF64{
+		H: h,
+		S: a.S + t*(b.S-a.S),
+		V: a.V + t*(b.V-a.V),
+		A: a.A + t*(b.A-a.A),
+	}
+}
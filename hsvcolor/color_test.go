@@ -172,6 +172,42 @@ func TestNHSVAToNRGBA(t *testing.T) {
 	}
 }
 
+// TestNHSVAFixedPoint confirms that NHSVA's fixed-point RGBA conversion
+// agrees with the floating-point conversion across all hues.
+func TestNHSVAFixedPoint(t *testing.T) {
+	for h := 0; h < 256; h++ {
+		for s := 1; s < 256; s += 17 {
+			for v := 0; v < 256; v += 15 {
+				for _, a := range []int{0, 1, 128, 254, 255} {
+					c := NHSVA{uint8(h), uint8(s), uint8(v), uint8(a)}
+					r1, g1, b1, a1 := c.RGBA()
+					r2, g2, b2, a2 := nhsvaFloat64ToRGBA(float64(h)*360.0/255.0, float64(s)/255.0, float64(v)/255.0, float64(a)/255.0)
+					for _, p := range [...][2]uint32{{r1, r2}, {g1, g2}, {b1, b2}, {a1, a2}} {
+						if p[0] > p[1]+1 || p[1] > p[0]+1 {
+							t.Fatalf("Expected %v to map to {%d, %d, %d, %d} but saw {%d, %d, %d, %d}", c, r2, g2, b2, a2, r1, g1, b1, a1)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkNHSVARGBA measures the cost of converting NHSVA colors to RGBA.
+func BenchmarkNHSVARGBA(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		NHSVA{uint8(n), 200, 150, 255}.RGBA()
+	}
+}
+
+// BenchmarkNHSVARGBAFloat measures the cost of converting NHSVA colors to
+// RGBA via floating-point arithmetic for comparison with BenchmarkNHSVARGBA.
+func BenchmarkNHSVARGBAFloat(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		nhsvaFloat64ToRGBA(float64(uint8(n))*360.0/255.0, 200.0/255.0, 150.0/255.0, 1.0)
+	}
+}
+
 // TestGrayHSV64ToRGB confirms that we can convert 64-bit grayscale HSV values
 // to RGB.
 func TestGrayHSV64ToRGB(t *testing.T) {
@@ -467,3 +503,481 @@ func TestNHSVAF64ToNRGBA(t *testing.T) {
 		}
 	}
 }
+
+// TestHueDistance confirms that hue distances wrap around the color wheel.
+func TestHueDistance(t *testing.T) {
+	red := NHSVA{0, 255, 255, 255}
+	for _, tc := range []struct {
+		H    uint8
+		Dist float64
+	}{
+		{0, 0.0},
+		{254, 360.0 / 255.0},
+		{255, 0.0},
+		{1, 360.0 / 255.0},
+		{85, 120.0},
+		{170, 120.0},
+	} {
+		d := red.HueDistance(NHSVA{tc.H, 255, 255, 255})
+		if !nearF64(d, tc.Dist) {
+			t.Fatalf("Expected distance %.3f from red to hue %d but saw %.3f", tc.Dist, tc.H, d)
+		}
+	}
+}
+
+// TestNearestNHSVA confirms that palette searches respect hue wraparound.
+func TestNearestNHSVA(t *testing.T) {
+	palette := []NHSVA{
+		{85, 255, 255, 255},  // Green
+		{170, 255, 255, 255}, // Blue
+		{250, 255, 255, 255}, // Nearly red
+		{20, 255, 255, 255},  // Orange
+		{0, 0, 128, 255},     // Gray
+	}
+	if i := NearestNHSVA(NHSVA{0, 255, 255, 255}, palette); i != 2 {
+		t.Fatalf("Expected red to be nearest palette entry 2 but saw %d", i)
+	}
+	if i := NearestNHSVA(NHSVA{100, 10, 120, 255}, palette); i != 4 {
+		t.Fatalf("Expected dark gray to be nearest palette entry 4 but saw %d", i)
+	}
+	if i := NearestNHSVA(NHSVA{}, nil); i != -1 {
+		t.Fatalf("Expected an empty palette to produce -1 but saw %d", i)
+	}
+}
+
+// TestNearGrayContinuity confirms that the grayscale fast path and the
+// general conversion path agree on brightness as saturation rises from zero.
+func TestNearGrayContinuity(t *testing.T) {
+	for ai := uint32(0); ai <= 255; ai += 15 {
+		a := uint8(ai)
+		for vi := uint32(0); vi <= 255; vi++ {
+			v := uint8(vi)
+			gr, gg, gb, ga := NHSVA{0, 0, v, a}.RGBA()
+			if gr != gg || gg != gb {
+				t.Fatalf("Expected gray from %v but saw {%d, %d, %d, %d}", NHSVA{0, 0, v, a}, gr, gg, gb, ga)
+			}
+			for s := uint8(1); s <= 4; s++ {
+				// With a hue of 0, red is the maximum
+				// channel and should equal the gray level.
+				c := NHSVA{0, s, v, a}
+				r, g, b, a32 := c.RGBA()
+				if r != gr || a32 != ga {
+					t.Fatalf("Expected red %d and alpha %d from %v but saw {%d, %d, %d, %d}", gr, ga, c, r, g, b, a32)
+				}
+				if g > r || b > r || r-g > 5*257 || r-b > 5*257 {
+					t.Fatalf("Expected %v to be nearly gray but saw {%d, %d, %d, %d}", c, r, g, b, a32)
+				}
+			}
+		}
+	}
+}
+
+// TestBitDepthConversions confirms that widening then narrowing an HSV color
+// without going through RGB recovers the original color.
+func TestBitDepthConversions(t *testing.T) {
+	for i := 0; i < 256; i++ {
+		c := NHSVA{uint8(i), uint8(255 - i), uint8(i * 7), uint8(i * 13)}
+		c64 := c.To64()
+		if c64.H != uint16(i)*257 {
+			t.Fatalf("Expected %v to widen to hue %d but saw %v", c, uint16(i)*257, c64)
+		}
+		want := c
+		if want.H == 255 {
+			want.H = 0 // 255 and 0 both represent red.
+		}
+		if c8 := c64.To8(); c8 != want {
+			t.Fatalf("Expected %v to narrow through %v to %v but saw %v", c, c64, want, c8)
+		}
+		if c8 := c.ToF64().To8(); c8 != want {
+			t.Fatalf("Expected %v to narrow through %v to %v but saw %v", c, c.ToF64(), want, c8)
+		}
+		if c8 := NHSVAModel.Convert(c64).(NHSVA); c8.H != c64.To8().H {
+			t.Fatalf("Expected NHSVAModel and To8 to agree on the hue of %v but saw %v and %v", c64, c8, c64.To8())
+		}
+		if c16 := c64.ToF64().To64(); c16 != c64 {
+			t.Fatalf("Expected %v to round-trip through %v but saw %v", c64, c64.ToF64(), c16)
+		}
+	}
+	if h := (NHSVA{85, 255, 255, 255}).ToF64().H; !nearF64(h, 120.0) {
+		t.Fatalf("Expected green to have a hue of 120 but saw %.5f", h)
+	}
+	if c := (NHSVAF64{-90.0, 1.5, -0.5, 1.0}).To64(); c != (NHSVA64{49151, 65535, 0, 65535}) {
+		t.Fatalf("Expected out-of-range channels to wrap and clamp but saw %v", c)
+	}
+}
+
+// TestLerpNHSVAF64 confirms that interpolation takes the shorter arc around
+// the color wheel.
+func TestLerpNHSVAF64(t *testing.T) {
+	a := NHSVAF64{350.0, 0.2, 0.4, 1.0}
+	b := NHSVAF64{10.0, 0.6, 0.8, 0.5}
+	c := LerpNHSVAF64(a, b, 0.5)
+	if math.Min(c.H, 360.0-c.H) > 1e-9 || !nearF64(c.S, 0.4) || !nearF64(c.V, 0.6) || !nearF64(c.A, 0.75) {
+		t.Fatalf("Expected a hue near 0 between %v and %v but saw %v", a, b, c)
+	}
+	if c = LerpNHSVAF64(b, a, 0.25); !nearF64(c.H, 5.0) {
+		t.Fatalf("Expected a hue of 5 but saw %v", c)
+	}
+	if c = LerpNHSVAF64(a, b, -1.0); c != a {
+		t.Fatalf("Expected t < 0 to produce %v but saw %v", a, c)
+	}
+	if c = LerpNHSVAF64(a, b, 2.0); !nearF64(c.H, 10.0) || c.S != b.S || c.V != b.V || c.A != b.A {
+		t.Fatalf("Expected t > 1 to produce %v but saw %v", b, c)
+	}
+	if c = LerpNHSVAF64(NHSVAF64{H: 60.0}, NHSVAF64{H: 180.0}, 0.5); !nearF64(c.H, 120.0) {
+		t.Fatalf("Expected a hue of 120 but saw %v", c)
+	}
+}
+
+// TestLerpUndefinedHue confirms that interpolating between a gray and a
+// colored pixel keeps the colored pixel's hue.
+func TestLerpUndefinedHue(t *testing.T) {
+	gray := NHSVAF64{0.0, 0.0, 0.5, 1.0}
+	blue := NHSVAF64{240.0, 1.0, 1.0, 1.0}
+	for _, tt := range []float64{0.0, 0.25, 0.5, 0.75, 1.0} {
+		c := LerpNHSVAF64(gray, blue, tt)
+		if !nearF64(c.H, 240.0) || !nearF64(c.S, tt) {
+			t.Fatalf("Expected hue 240 and saturation %.2f but saw %v", tt, c)
+		}
+		if c = LerpNHSVAF64(blue, gray, tt); !nearF64(c.H, 240.0) {
+			t.Fatalf("Expected hue 240 but saw %v", c)
+		}
+	}
+}
+
+// TestHueSweep8vs64 confirms that converting saturated colors to NHSVA and to
+// NHSVA64 produces hues that lie within one 8-bit step of each other, even
+// near the 0/360 boundary.
+func TestHueSweep8vs64(t *testing.T) {
+	for d := 0.0; d < 360.0; d += 0.25 {
+		rgb := color.RGBA64Model.Convert(NHSVAF64{d, 1.0, 1.0, 1.0})
+		h8 := NHSVAModel.Convert(rgb).(NHSVA).H
+		h64 := NHSVA64Model.Convert(rgb).(NHSVA64).H
+		if h8 == 255 {
+			t.Fatalf("Expected hue %.2f to map to 0 rather than 255", d)
+		}
+		diff := math.Abs(float64(h8) - float64(h64)*255.0/65535.0)
+		if diff > 255.0/2.0 {
+			diff = 255.0 - diff
+		}
+		if diff > 1.0 {
+			t.Fatalf("Hue %.2f mapped to 8-bit %d and 64-bit %d", d, h8, h64)
+		}
+	}
+}
+
+// TestGrayHSVF32ToRGB confirms that we can convert 32-bit floating-point
+// grayscale HSV values to RGB.
+func TestGrayHSVF32ToRGB(t *testing.T) {
+	for vi := uint32(0); vi <= 65535; vi += 255 {
+		v := uint16(vi)
+		hsv := NHSVAF32{0.0, 0.0, float32(vi) / 65535.0, 1.0}
+		r32, g32, b32, a32 := hsv.RGBA()
+		r, g, b, a := uint16(r32), uint16(g32), uint16(b32), uint16(a32)
+		if r != v || g != v || b != v || a != 65535 {
+			t.Fatalf("Incorrectly mapped %#v to {%d, %d, %d, %d}",
+				hsv, r, g, b, a)
+		}
+	}
+}
+
+// TestGrayToHSVF32 confirms that we can convert grayscale values to 32-bit
+// floating-point HSV.
+func TestGrayToHSVF32(t *testing.T) {
+	for vi := uint32(0); vi <= 65535; vi++ {
+		g := color.Gray16{uint16(vi)}
+		hsv := NHSVAF32Model.Convert(g).(NHSVAF32)
+		if hsv.H != 0.0 || hsv.S != 0.0 || hsv.V != float32(float64(g.Y)/65535.0) || hsv.A != 1.0 {
+			t.Fatalf("Incorrectly mapped %#v to %#v", g, hsv)
+		}
+	}
+}
+
+// TestNRGBToNHSVF32 confirms that we can convert non-premultiplied RGB to
+// non-premultiplied 32-bit floating-point HSV, with no transparency in
+// either.
+func TestNRGBToNHSVF32(t *testing.T) {
+	for _, cEq := range colorEquivalencesF64 {
+		nrgba := color.NRGBA{cEq.RGB[0], cEq.RGB[1], cEq.RGB[2], 255}
+		nhsva := NHSVAF32Model.Convert(nrgba).(NHSVAF32)
+		if !nearF64(float64(nhsva.H), cEq.HSV[0]) || !nearF64(float64(nhsva.S), cEq.HSV[1]) || !nearF64(float64(nhsva.V), cEq.HSV[2]) || !nearF64(float64(nhsva.A), 1.0) {
+			t.Fatalf("Incorrectly mapped %s from %v to %v (expected %v)", cEq.Name, nrgba, nhsva, cEq.HSV)
+		}
+	}
+}
+
+// TestNHSVAF32ToNRGBA confirms that we can convert non-premultiplied 32-bit
+// floating-point HSV to premultiplied 64-bit RGB, with transparency preserved.
+func TestNHSVAF32ToNRGBA(t *testing.T) {
+	for ai := uint32(0); ai <= 255; ai += 15 {
+		aOrig := float32(ai) / 255.0
+		for _, cEq := range colorEquivalencesF64 {
+			nhsva := NHSVAF32{float32(cEq.HSV[0]), float32(cEq.HSV[1]), float32(cEq.HSV[2]), aOrig}
+			rp16, gp16, bp16, a16 := nhsva.RGBA()
+			if a16 == 0 {
+				// Special case for fully transparent colors.
+				if rp16 != 0 || gp16 != 0 || bp16 != 0 || ai != 0 {
+					t.Fatalf("Incorrectly mapped full-transparent %s from %v to [%d %d %d %d] (expected [0 0 0 0])", cEq.Name, nhsva, rp16, gp16, bp16, a16)
+				}
+				continue
+			}
+			var r, g, b uint8
+			a := uint8(a16 >> 8)
+			a16half := a16 / 2
+			r = uint8((255*rp16 + a16half) / a16)
+			g = uint8((255*gp16 + a16half) / a16)
+			b = uint8((255*bp16 + a16half) / a16)
+			if !near(r, cEq.RGB[0]) || !near(g, cEq.RGB[1]) || !near(b, cEq.RGB[2]) || a != uint8(ai) {
+				t.Fatalf("Incorrectly mapped %s from %v to [%d %d %d %d] (expected %v + %d)", cEq.Name, nhsva, r, g, b, a, cEq.RGB, uint8(ai))
+			}
+		}
+	}
+}
+
+// TestInGamut confirms that InGamut distinguishes in-range from out-of-range
+// NHSVAF64 colors.
+func TestInGamut(t *testing.T) {
+	for _, c := range []NHSVAF64{
+		{H: 0.0, S: 0.0, V: 0.0, A: 0.0},
+		{H: 359.9, S: 1.0, V: 1.0, A: 1.0},
+		{H: 180.0, S: 0.5, V: 0.25, A: 0.75},
+	} {
+		if !c.InGamut() {
+			t.Fatalf("Expected %v to be in gamut", c)
+		}
+	}
+	for _, c := range []NHSVAF64{
+		{H: 360.0, S: 0.5, V: 0.5, A: 1.0},
+		{H: -10.0, S: 0.5, V: 0.5, A: 1.0},
+		{H: 90.0, S: 1.5, V: 0.5, A: 1.0},
+		{H: 90.0, S: 0.5, V: -0.1, A: 1.0},
+		{H: 90.0, S: 0.5, V: 0.5, A: 1.01},
+	} {
+		if c.InGamut() {
+			t.Fatalf("Expected %v to be out of gamut", c)
+		}
+	}
+}
+
+// referenceHue computes the hue in degrees of a non-premultiplied RGB color
+// using the formulation found in, e.g., the go-colorful library.
+func referenceHue(r, g, b float64) float64 {
+	cMin := math.Min(math.Min(r, g), b)
+	cMax := math.Max(math.Max(r, g), b)
+	d := cMax - cMin
+	if d == 0.0 {
+		return 0.0
+	}
+	var h float64
+	switch {
+	case r == cMax:
+		h = (g - b) / d
+	case g == cMax:
+		h = 2.0 + (b-r)/d
+	default:
+		h = 4.0 + (r-g)/d
+	}
+	h *= 60.0
+	if h < 0.0 {
+		h += 360.0
+	}
+	return h
+}
+
+// TestMaxChannelTies confirms that colors with two channels tied for the
+// maximum convert to a stable hue that agrees with a reference
+// implementation.
+func TestMaxChannelTies(t *testing.T) {
+	for hi := 1; hi <= 255; hi += 2 {
+		for lo := 0; lo < hi; lo += 3 {
+			h8, l8 := uint8(hi), uint8(lo)
+			for _, rgb := range [][3]uint8{
+				{h8, h8, l8}, // Yellow
+				{l8, h8, h8}, // Cyan
+				{h8, l8, h8}, // Magenta
+			} {
+				ref := referenceHue(float64(rgb[0]), float64(rgb[1]), float64(rgb[2]))
+				nrgba := color.NRGBA{rgb[0], rgb[1], rgb[2], 255}
+				c64 := NHSVA64Model.Convert(nrgba).(NHSVA64)
+				if d := math.Abs(float64(c64.H)*360.0/65535.0 - ref); d > 0.01 {
+					t.Fatalf("Expected %v to have NHSVA64 hue %.3f° but saw %.3f°", nrgba, ref, float64(c64.H)*360.0/65535.0)
+				}
+				cF64 := NHSVAF64Model.Convert(nrgba).(NHSVAF64)
+				if math.Abs(cF64.H-ref) > 1e-9 {
+					t.Fatalf("Expected %v to have NHSVAF64 hue %.3f° but saw %.3f°", nrgba, ref, cF64.H)
+				}
+
+				// Nudging the tied channels apart should barely
+				// move the hue.
+				if hi < 255 || lo > 0 {
+					continue
+				}
+				for k := 0; k < 3; k++ {
+					if rgb[k] != h8 {
+						continue
+					}
+					nudged := nrgba
+					switch k {
+					case 0:
+						nudged.R--
+					case 1:
+						nudged.G--
+					case 2:
+						nudged.B--
+					}
+					nF64 := NHSVAF64Model.Convert(nudged).(NHSVAF64)
+					if d := math.Abs(nF64.H - cF64.H); d > 0.5 {
+						t.Fatalf("Expected %v and %v to have similar hues but saw %.3f° and %.3f°", nrgba, nudged, cF64.H, nF64.H)
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestPerceivedLuma confirms that PerceivedLuma weights colors by their
+// perceived brightness.
+func TestPerceivedLuma(t *testing.T) {
+	yellow := NHSVA{H: 43, S: 255, V: 255, A: 255}
+	blue := NHSVA{H: 170, S: 255, V: 255, A: 255}
+	if ly, lb := yellow.PerceivedLuma(), blue.PerceivedLuma(); ly <= lb {
+		t.Fatalf("Expected yellow (%d) to be brighter than blue (%d)", ly, lb)
+	}
+	for _, tc := range []struct {
+		c    NHSVA
+		luma uint8
+	}{
+		{NHSVA{H: 0, S: 0, V: 0, A: 255}, 0},
+		{NHSVA{H: 0, S: 0, V: 255, A: 255}, 255},
+		{NHSVA{H: 0, S: 0, V: 128, A: 10}, 128},
+		{NHSVA{H: 0, S: 255, V: 255, A: 255}, 54},
+		{NHSVA{H: 85, S: 255, V: 255, A: 255}, 182},
+		{NHSVA{H: 170, S: 255, V: 255, A: 255}, 18},
+	} {
+		if l := tc.c.PerceivedLuma(); l != tc.luma {
+			t.Fatalf("Expected %v to have luma %d but saw %d", tc.c, tc.luma, l)
+		}
+	}
+}
+
+// TestHueDegrees confirms that hues round-trip through NHSVAFromDegrees and
+// HueDegrees to within the precision of an 8-bit hue.
+func TestHueDegrees(t *testing.T) {
+	for _, tc := range []struct {
+		deg float64
+		h   uint8
+	}{
+		{0.0, 0},
+		{120.0, 85},
+		{240.0, 170},
+		{359.0, 254},
+		{359.9, 0},
+		{-120.0, 170},
+		{480.0, 85},
+	} {
+		c := NHSVAFromDegrees(tc.deg, 10, 20, 30)
+		if c != (NHSVA{H: tc.h, S: 10, V: 20, A: 30}) {
+			t.Fatalf("Expected %.1f° to map to hue %d but saw %v", tc.deg, tc.h, c)
+		}
+		if tc.deg < 0.0 || tc.deg >= 359.5 {
+			continue
+		}
+		if d := c.HueDegrees(); math.Abs(d-tc.deg) > 180.0/255.0 {
+			t.Fatalf("Expected %.1f° to round-trip but saw %.3f°", tc.deg, d)
+		}
+	}
+}
+
+// TestHueDefined confirms that only grays are reported as having an undefined
+// hue.
+func TestHueDefined(t *testing.T) {
+	for _, tc := range []struct {
+		c   NHSVA
+		def bool
+	}{
+		{NHSVA{0, 0, 0, 255}, false},
+		{NHSVA{0, 0, 255, 255}, false},
+		{NHSVA{100, 0, 128, 64}, false},
+		{NHSVA{0, 1, 128, 255}, true},
+		{NHSVA{0, 255, 255, 255}, true},
+		{NHSVA{170, 128, 0, 0}, true},
+	} {
+		if d := tc.c.HueDefined(); d != tc.def {
+			t.Fatalf("Expected HueDefined to return %v for %v but saw %v", tc.def, tc.c, d)
+		}
+		if d := tc.c.ToF64().HueDefined(); d != tc.def {
+			t.Fatalf("Expected HueDefined to return %v for %v but saw %v", tc.def, tc.c.ToF64(), d)
+		}
+	}
+
+	// Grays produced by the color model should have a hue of 0.
+	for _, g := range []uint8{0, 77, 255} {
+		c := NHSVAModel.Convert(color.Gray{Y: g}).(NHSVA)
+		if c.HueDefined() || c.H != 0 {
+			t.Fatalf("Expected gray %d to map to an undefined hue of 0 but saw %v", g, c)
+		}
+	}
+}
+
+// TestAnalogous confirms that analogous color schemes are evenly spaced,
+// symmetric around the base hue, and wrap around the color wheel.
+func TestAnalogous(t *testing.T) {
+	base := NHSVA{H: 10, S: 200, V: 150, A: 100}
+	for _, tc := range []struct {
+		count  int
+		spread uint8
+		hues   []uint8
+	}{
+		{1, 20, []uint8{10}},
+		{3, 20, []uint8{245, 10, 30}},
+		{5, 8, []uint8{249, 2, 10, 18, 26}},
+		{2, 30, []uint8{250, 25}},
+		{4, 7, []uint8{0, 7, 13, 20}},
+	} {
+		scheme := base.Analogous(tc.count, tc.spread)
+		if len(scheme) != tc.count {
+			t.Fatalf("Expected %d colors but saw %d", tc.count, len(scheme))
+		}
+		for k, c := range scheme {
+			if c != (NHSVA{H: tc.hues[k], S: 200, V: 150, A: 100}) {
+				t.Fatalf("Expected hue %d at index %d of %v but saw %v", tc.hues[k], k, tc.hues, c)
+			}
+			mirror := scheme[len(scheme)-1-k]
+			d0 := math.Mod(c.HueDegrees()-base.HueDegrees()+540.0, 360.0) - 180.0
+			d1 := math.Mod(mirror.HueDegrees()-base.HueDegrees()+540.0, 360.0) - 180.0
+			if math.Abs(d0+d1) > 1e-9 {
+				t.Fatalf("Expected %v and %v to be symmetric around hue %d but saw offsets of %.3f° and %.3f°", c, mirror, base.H, d0, d1)
+			}
+		}
+	}
+	if scheme := base.Analogous(0, 10); scheme != nil {
+		t.Fatalf("Expected no colors but saw %v", scheme)
+	}
+}
+
+// TestNHSVAF64FromHSV confirms that NHSVAF64FromHSV accepts in-range
+// channels as is, wraps hue, clamps the other channels, and round-trips
+// through ToColorfulHSV.
+func TestNHSVAF64FromHSV(t *testing.T) {
+	for _, tc := range []struct {
+		in  [4]float64
+		out NHSVAF64
+	}{
+		{[4]float64{0.0, 0.0, 0.0, 0.0}, NHSVAF64{0.0, 0.0, 0.0, 0.0}},
+		{[4]float64{123.5, 0.25, 0.75, 1.0}, NHSVAF64{123.5, 0.25, 0.75, 1.0}},
+		{[4]float64{360.0, 0.5, 0.5, 0.5}, NHSVAF64{0.0, 0.5, 0.5, 0.5}},
+		{[4]float64{-90.0, 1.5, -0.5, 2.0}, NHSVAF64{270.0, 1.0, 0.0, 1.0}},
+		{[4]float64{750.0, -1.0, 3.0, -0.25}, NHSVAF64{30.0, 0.0, 1.0, 0.0}},
+	} {
+		c := NHSVAF64FromHSV(tc.in[0], tc.in[1], tc.in[2], tc.in[3])
+		if c != tc.out || !c.InGamut() {
+			t.Fatalf("Expected %v to map to %v but saw %v", tc.in, tc.out, c)
+		}
+		if h, s, v := c.ToColorfulHSV(); h != c.H || s != c.S || v != c.V {
+			t.Fatalf("Expected %v to produce (%v, %v, %v) but saw (%v, %v, %v)", c, c.H, c.S, c.V, h, s, v)
+		}
+	}
+	if c := NHSVAF64FromHSV(-1e-20, 0.5, 0.5, 1.0); c.H != 0.0 {
+		t.Fatalf("Expected a tiny negative hue to wrap to 0 but saw %v", c)
+	}
+}
@@ -0,0 +1,98 @@
+package hsvcolor
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// parseHSVComponent parses a single numeric component of an hsv() or hsva()
+// string.  If pct is true, the component must end in "%".  The component must
+// lie in [0, max].
+func parseHSVComponent(s, name string, pct bool, max float64) (float64, error) {
+	s = strings.TrimSpace(s)
+	if pct {
+		if !strings.HasSuffix(s, "%") {
+			return 0.0, fmt.Errorf("hsvcolor: %s %q is not a percentage", name, s)
+		}
+		s = strings.TrimSpace(strings.TrimSuffix(s, "%"))
+	}
+	x, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0.0, fmt.Errorf("hsvcolor: %s %q is not a number", name, s)
+	}
+	if math.IsNaN(x) || x < 0.0 || x > max {
+		return 0.0, fmt.Errorf("hsvcolor: %s %q is out of range [0, %g]", name, s, max)
+	}
+	return x, nil
+}
+
+// ParseHSV parses a string of the form "hsv(H, S%, V%)" or
+// "hsva(H, S%, V%, A)" into an NHSVA color.  H is a hue in [0, 360], S and V
+// are percentages in [0, 100], and A is an alpha value in [0, 1].  Whitespace
+// is permitted around each component, and the function name is
+// case-insensitive.  Colors parsed from "hsv(...)" are fully opaque.
+func ParseHSV(s string) (NHSVA, error) {
+	// Split the string into a function name and a list of arguments.
+	str := strings.TrimSpace(s)
+	open := strings.IndexByte(str, '(')
+	if open < 0 || !strings.HasSuffix(str, ")") {
+		return NHSVA{}, fmt.Errorf("hsvcolor: %q is not of the form hsv(...) or hsva(...)", s)
+	}
+	fn := strings.ToLower(strings.TrimSpace(str[:open]))
+	args := strings.Split(str[open+1:len(str)-1], ",")
+	var nArgs int
+	switch fn {
+	case "hsv":
+		nArgs = 3
+	case "hsva":
+		nArgs = 4
+	default:
+		return NHSVA{}, fmt.Errorf("hsvcolor: unrecognized color function %q in %q", fn, s)
+	}
+	if len(args) != nArgs {
+		return NHSVA{}, fmt.Errorf("hsvcolor: %s() in %q takes %d arguments, not %d", fn, s, nArgs, len(args))
+	}
+
+	// Parse each argument in turn.
+	h, err := parseHSVComponent(args[0], "hue", false, 360.0)
+	if err != nil {
+		return NHSVA{}, err
+	}
+	sat, err := parseHSVComponent(args[1], "saturation", true, 100.0)
+	if err != nil {
+		return NHSVA{}, err
+	}
+	v, err := parseHSVComponent(args[2], "value", true, 100.0)
+	if err != nil {
+		return NHSVA{}, err
+	}
+	a := 1.0
+	if len(args) == 4 {
+		a, err = parseHSVComponent(args[3], "alpha", false, 1.0)
+		if err != nil {
+			return NHSVA{}, err
+		}
+	}
+
+	// Scale each component to [0, 255].
+	return NHSVA{
+		H: uint8(math.Round(h * 255.0 / 360.0)),
+		S: uint8(math.Round(sat * 255.0 / 100.0)),
+		V: uint8(math.Round(v * 255.0 / 100.0)),
+		A: uint8(math.Round(a * 255.0)),
+	}, nil
+}
+
+// String returns an NHSVA color in the form "hsva(H, S%, V%, A)", with H
+// rounded to an integer in [0, 359], S and V rounded to integer percentages,
+// and A expressed as a fraction with two digits after the decimal point.  The
+// result is accepted by ParseHSV.
+func (c NHSVA) String() string {
+	h := int(math.Round(float64(c.H)*360.0/255.0)) % 360
+	s := int(math.Round(float64(c.S) * 100.0 / 255.0))
+	v := int(math.Round(float64(c.V) * 100.0 / 255.0))
+	a := float64(c.A) / 255.0
+	return fmt.Sprintf("hsva(%d, %d%%, %d%%, %.2f)", h, s, v, a)
+}
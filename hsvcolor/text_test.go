@@ -0,0 +1,96 @@
+// This file tests conversions between HSV colors and textual notation.
+
+package hsvcolor
+
+import (
+	"testing"
+)
+
+// TestParseHSV confirms that we can parse well-formed hsv() and hsva()
+// strings.
+func TestParseHSV(t *testing.T) {
+	for _, tc := range []struct {
+		Str   string
+		Color NHSVA
+	}{
+		{"hsv(120,100%,50%)", NHSVA{85, 255, 128, 255}},
+		{"hsva(120,100%,50%,0.5)", NHSVA{85, 255, 128, 128}},
+		{"  HSV( 0 , 0% , 100% ) ", NHSVA{0, 0, 255, 255}},
+		{"hsva(360, 25%, 0%, 0)", NHSVA{255, 64, 0, 0}},
+		{"hsv(240, 100 %, 100%)", NHSVA{170, 255, 255, 255}},
+	} {
+		c, err := ParseHSV(tc.Str)
+		if err != nil {
+			t.Fatalf("Failed to parse %q (%s)", tc.Str, err)
+		}
+		if c != tc.Color {
+			t.Fatalf("Expected %q to parse as %v but saw %v", tc.Str, tc.Color, c)
+		}
+	}
+}
+
+// TestParseHSVErrors confirms that we reject malformed hsv() and hsva()
+// strings.
+func TestParseHSVErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"hsv",
+		"hsv(120, 100%, 50%",
+		"rgb(120, 100%, 50%)",
+		"hsv(120, 100%)",
+		"hsv(120, 100%, 50%, 1)",
+		"hsva(120, 100%, 50%)",
+		"hsv(120, 100, 50%)",
+		"hsv(abc, 100%, 50%)",
+		"hsv(400, 100%, 50%)",
+		"hsv(-1, 100%, 50%)",
+		"hsv(120, 101%, 50%)",
+		"hsva(120, 100%, 50%, 1.5)",
+		"hsva(120, 100%, 50%, 50%)",
+	} {
+		if c, err := ParseHSV(s); err == nil {
+			t.Fatalf("Expected %q to be rejected but it parsed as %v", s, c)
+		}
+	}
+}
+
+// TestNHSVAString confirms that NHSVA colors are formatted in conventional
+// units.
+func TestNHSVAString(t *testing.T) {
+	expected := map[string]string{
+		"black":       "hsva(0, 0%, 0%, 1.00)",
+		"white":       "hsva(0, 0%, 100%, 1.00)",
+		"red":         "hsva(0, 100%, 100%, 1.00)",
+		"green":       "hsva(120, 100%, 100%, 1.00)",
+		"blue":        "hsva(240, 100%, 100%, 1.00)",
+		"yellow":      "hsva(61, 100%, 100%, 1.00)",
+		"cyan":        "hsva(181, 100%, 100%, 1.00)",
+		"magenta":     "hsva(301, 100%, 100%, 1.00)",
+		"dark blue":   "hsva(240, 100%, 50%, 1.00)",
+		"pale yellow": "hsva(61, 25%, 100%, 1.00)",
+	}
+	for _, cEq := range colorEquivalences {
+		c := NHSVA{cEq.HSV[0], cEq.HSV[1], cEq.HSV[2], 255}
+		if str := c.String(); str != expected[cEq.Name] {
+			t.Fatalf("Expected %s to be formatted as %q but saw %q", cEq.Name, expected[cEq.Name], str)
+		}
+	}
+
+	// Check hue wraparound and partial transparency.
+	if str := (NHSVA{255, 255, 255, 128}).String(); str != "hsva(0, 100%, 100%, 0.50)" {
+		t.Fatalf("Expected %q but saw %q", "hsva(0, 100%, 100%, 0.50)", str)
+	}
+
+	// Confirm that formatting and parsing round-trip, modulo the loss of
+	// precision from formatting integral percentages.
+	for _, cEq := range colorEquivalences {
+		c := NHSVA{cEq.HSV[0], cEq.HSV[1], cEq.HSV[2], 255}
+		c2, err := ParseHSV(c.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !near(c2.H, c.H) || !near(c2.S, c.S) || !near(c2.V, c.V) || c2.A != c.A {
+			t.Fatalf("Expected %s to round-trip through %q but saw %#v", cEq.Name, c.String(), c2)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package hsvimage
+
+import (
+	"math"
+)
+
+// hueDegrees converts an 8-bit hue to degrees in [0, 360].
+func hueDegrees(h uint8) float64 {
+	return float64(h) * 360.0 / 255.0
+}
+
+// hueFromDegrees converts a hue in degrees, in [0, 360), to an 8-bit hue.
+func hueFromDegrees(d float64) uint8 {
+	return uint8(math.Round(d * 255.0 / 360.0))
+}
+
+// wrapDegrees maps a hue in degrees onto [0, 360).
+func wrapDegrees(h float64) float64 {
+	h = math.Mod(h, 360.0)
+	if h < 0.0 {
+		h += 360.0
+	}
+	if h >= 360.0 {
+		h = 0.0 // Tiny negative hues can round up to 360.
+	}
+	return h
+}
+
+// hueDistance returns the angular distance in degrees, in [0, 180], between
+// two hues expressed in degrees.
+func hueDistance(a, b float64) float64 {
+	d := math.Mod(math.Abs(a-b), 360.0)
+	if d > 180.0 {
+		d = 360.0 - d
+	}
+	return d
+}
+
+// A hueSum accumulates weighted hues as vectors on the unit circle so that
+// their mean respects wraparound.
+type hueSum struct {
+	x, y float64
+}
+
+// add includes a hue, expressed in degrees, with the given weight.
+func (hs *hueSum) add(h, w float64) {
+	hr := h * math.Pi / 180.0
+	hs.x += w * math.Cos(hr)
+	hs.y += w * math.Sin(hr)
+}
+
+// mean returns the circular mean of the accumulated hues in degrees, in
+// [0, 360).  It returns 0 if the hues cancel out.
+func (hs hueSum) mean() float64 {
+	if hs.x == 0.0 && hs.y == 0.0 {
+		return 0.0
+	}
+	h := math.Atan2(hs.y, hs.x) * 180.0 / math.Pi
+	if h < 0.0 {
+		h += 360.0
+	}
+	return h
+}
+
+// hueDelta returns the signed angular difference b-a, in degrees, between two
+// hues expressed in degrees.  The result lies in (-180, 180].
+func hueDelta(a, b float64) float64 {
+	d := math.Mod(b-a, 360.0)
+	switch {
+	case d > 180.0:
+		d -= 360.0
+	case d <= -180.0:
+		d += 360.0
+	}
+	return d
+}
+
+// hueInRange reports whether an 8-bit hue lies within [lo, hi].  If lo > hi,
+// the range wraps around the color wheel through red.
+func hueInRange(h, lo, hi uint8) bool {
+	if lo <= hi {
+		return h >= lo && h <= hi
+	}
+	return h >= lo || h <= hi
+}
+
+// hueDiff8 returns the signed difference b-a between two 8-bit hues, measured
+// the short way around the color wheel, in [-127, 127].  Like hueDegrees, it
+// treats the wheel as having 255 steps, so hues 255 and 0 both represent red.
+func hueDiff8(a, b uint8) int {
+	d := (int(b) - int(a)) % 255
+	switch {
+	case d > 127:
+		d -= 255
+	case d < -127:
+		d += 255
+	}
+	return d
+}
+
+// hueAdd8 rotates an 8-bit hue by delta steps around the 255-step color
+// wheel.  The result lies in [0, 254].
+func hueAdd8(h uint8, delta int) uint8 {
+	r := (int(h) + delta) % 255
+	if r < 0 {
+		r += 255
+	}
+	return uint8(r)
+}
@@ -5,6 +5,8 @@ The code was largely adapted from code in the Go standard library.
 package hsvimage
 
 import (
+	"bytes"
+	"fmt"
 	"github.com/spakin/hsvimage/hsvcolor"
 	"image"
 	"image/color"
@@ -19,6 +21,8 @@ type NHSVA struct {
 	Stride int
 	// Rect is the image's bounds.
 	Rect image.Rectangle
+	// opaqueHint, if true, records that every pixel is known to be opaque.
+	opaqueHint bool
 }
 
 // ColorModel states that an NHSVA image uses the hsvcolor.NHSVA color model.
@@ -43,6 +47,17 @@ func (p *NHSVA) NHSVAAt(x, y int) hsvcolor.NHSVA {
 	return hsvcolor.NHSVA{H: s[0], S: s[1], V: s[2], A: s[3]}
 }
 
+// NHSVAAtChecked is like NHSVAAt but additionally reports whether (x, y) lies
+// within the image's bounds.  This distinguishes a genuinely transparent black
+// pixel from an out-of-bounds access, both of which NHSVAAt reports as the
+// zero color.
+func (p *NHSVA) NHSVAAtChecked(x, y int) (hsvcolor.NHSVA, bool) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return hsvcolor.NHSVA{}, false
+	}
+	return p.NHSVAAt(x, y), true
+}
+
 // PixOffset returns the index of the first element of Pix that corresponds to
 // the pixel at (x, y).
 func (p *NHSVA) PixOffset(x, y int) int {
@@ -61,6 +76,9 @@ func (p *NHSVA) Set(x, y int, c color.Color) {
 	s[1] = c1.S
 	s[2] = c1.V
 	s[3] = c1.A
+	if c1.A != 0xff {
+		p.opaqueHint = false
+	}
 }
 
 // SetNHSVA assigns an NHSVA color to a given coordinate.
@@ -74,6 +92,43 @@ func (p *NHSVA) SetNHSVA(x, y int, c hsvcolor.NHSVA) {
 	s[1] = c.S
 	s[2] = c.V
 	s[3] = c.A
+	if c.A != 0xff {
+		p.opaqueHint = false
+	}
+}
+
+// SetRow assigns a run of NHSVA colors to consecutive pixels starting at
+// (xs, y).  The run is clipped to the image bounds, and only the in-bounds
+// portion is written.
+func (p *NHSVA) SetRow(y int, xs int, colors []hsvcolor.NHSVA) {
+	if y < p.Rect.Min.Y || y >= p.Rect.Max.Y {
+		return
+	}
+	if xs < p.Rect.Min.X {
+		skip := p.Rect.Min.X - xs
+		if skip >= len(colors) {
+			return
+		}
+		colors = colors[skip:]
+		xs = p.Rect.Min.X
+	}
+	if n := p.Rect.Max.X - xs; n < len(colors) {
+		if n <= 0 {
+			return
+		}
+		colors = colors[:n]
+	}
+	i := p.PixOffset(xs, y)
+	s := p.Pix[i : i+4*len(colors) : i+4*len(colors)]
+	for j, c := range colors {
+		s[j*4+0] = c.H
+		s[j*4+1] = c.S
+		s[j*4+2] = c.V
+		s[j*4+3] = c.A
+		if c.A != 0xff {
+			p.opaqueHint = false
+		}
+	}
 }
 
 // SubImage returns an image representing the portion of the image p visible
@@ -95,8 +150,29 @@ func (p *NHSVA) SubImage(r image.Rectangle) image.Image {
 	}
 }
 
-// Opaque scans the entire image and reports whether it is fully opaque.
+// SetOpaqueHint lets Opaque avoid rescanning an image that is known to be
+// fully opaque, as when an unchanging image is checked once per frame in a
+// render loop.  SetOpaqueHint(true) scans the image once and, if every pixel
+// is opaque, records that fact so that subsequent calls to Opaque return true
+// immediately.  SetOpaqueHint(false) discards the record.  Set, SetNHSVA,
+// SetRow, and this package's methods that reduce alpha discard the record
+// whenever they write a non-opaque pixel.  Writes made directly to Pix or
+// through another image sharing the same pixels, such as one returned by
+// SubImage, bypass this check, so callers making such writes must call
+// SetOpaqueHint(false) themselves.
+func (p *NHSVA) SetOpaqueHint(opaque bool) {
+	p.opaqueHint = false
+	if opaque {
+		p.opaqueHint = p.Opaque()
+	}
+}
+
+// Opaque scans the entire image and reports whether it is fully opaque.  The
+// scan is skipped if SetOpaqueHint has recorded that the image is opaque.
 func (p *NHSVA) Opaque() bool {
+	if p.opaqueHint {
+		return true
+	}
 	if p.Rect.Empty() {
 		return true
 	}
@@ -113,11 +189,49 @@ func (p *NHSVA) Opaque() bool {
 	return true
 }
 
+// Equal reports whether two images have the same bounds and identical pixels
+// within those bounds.  Only the visible regions are compared, so two
+// sub-images of different parents can be equal.
+func (p *NHSVA) Equal(other *NHSVA) bool {
+	if !p.Rect.Eq(other.Rect) {
+		return false
+	}
+	if p.Rect.Empty() {
+		return true
+	}
+	n := 4 * p.Rect.Dx()
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i, j := p.PixOffset(p.Rect.Min.X, y), other.PixOffset(p.Rect.Min.X, y)
+		if !bytes.Equal(p.Pix[i:i+n], other.Pix[j:j+n]) {
+			return false
+		}
+	}
+	return true
+}
+
 // NewNHSVA returns a new NHSVA image with the given bounds.
 func NewNHSVA(r image.Rectangle) *NHSVA {
 	w, h := r.Dx(), r.Dy()
 	pix := make([]uint8, 4*w*h)
-	return &NHSVA{pix, 4 * w, r}
+	return &NHSVA{Pix: pix, Stride: 4 * w, Rect: r}
+}
+
+// NewNHSVAWithPix returns a new NHSVA image with the given bounds that uses
+// pix, with the given stride, as its pixel buffer.  The image shares pix with
+// the caller.  NewNHSVAWithPix returns an error if the stride is too small for
+// the image's width or if pix is too short to hold the image.
+func NewNHSVAWithPix(r image.Rectangle, pix []uint8, stride int) (*NHSVA, error) {
+	w, h := r.Dx(), r.Dy()
+	if w <= 0 || h <= 0 {
+		return &NHSVA{Pix: pix, Stride: stride, Rect: r}, nil
+	}
+	if stride < 4*w {
+		return nil, fmt.Errorf("hsvimage: stride %d is too small for width %d", stride, w)
+	}
+	if need := (h-1)*stride + 4*w; len(pix) < need {
+		return nil, fmt.Errorf("hsvimage: %d-byte pixel buffer is too short for bounds %v and stride %d (need %d bytes)", len(pix), r, stride, need)
+	}
+	return &NHSVA{Pix: pix, Stride: stride, Rect: r}, nil
 }
 
 // NHSVA64 is an in-memory image whose At method returns hsvcolor.NHSVA64 values.
@@ -200,6 +314,95 @@ func (p *NHSVA64) SetNHSVA64(x, y int, c hsvcolor.NHSVA64) {
 	s[7] = uint8(c.A)
 }
 
+// ForEachPixel calls fn on each pixel within the image's bounds, passing it
+// the pixel's coordinates and color, and replaces the pixel's color with fn's
+// return value.
+func (p *NHSVA64) ForEachPixel(fn func(x, y int, c hsvcolor.NHSVA64) hsvcolor.NHSVA64) {
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+8 {
+			s := p.Pix[i : i+8 : i+8] // Small cap improves performance, see https://golang.org/issue/27857
+			c := fn(x, y, hsvcolor.NHSVA64{
+				H: uint16(s[0])<<8 | uint16(s[1]),
+				S: uint16(s[2])<<8 | uint16(s[3]),
+				V: uint16(s[4])<<8 | uint16(s[5]),
+				A: uint16(s[6])<<8 | uint16(s[7]),
+			})
+			s[0] = uint8(c.H >> 8)
+			s[1] = uint8(c.H)
+			s[2] = uint8(c.S >> 8)
+			s[3] = uint8(c.S)
+			s[4] = uint8(c.V >> 8)
+			s[5] = uint8(c.V)
+			s[6] = uint8(c.A >> 8)
+			s[7] = uint8(c.A)
+		}
+	}
+}
+
+// Clear sets every pixel within the image's bounds to the given color.  It
+// writes the color once then repeatedly doubles the filled region with copy,
+// which is much faster than calling SetNHSVA64 on each pixel.
+func (p *NHSVA64) Clear(c hsvcolor.NHSVA64) {
+	w, h := p.Rect.Dx(), p.Rect.Dy()
+	if w <= 0 || h <= 0 {
+		return
+	}
+
+	// Fill the first row by copy-doubling.
+	i0 := p.PixOffset(p.Rect.Min.X, p.Rect.Min.Y)
+	row := p.Pix[i0 : i0+8*w : i0+8*w]
+	row[0] = uint8(c.H >> 8)
+	row[1] = uint8(c.H)
+	row[2] = uint8(c.S >> 8)
+	row[3] = uint8(c.S)
+	row[4] = uint8(c.V >> 8)
+	row[5] = uint8(c.V)
+	row[6] = uint8(c.A >> 8)
+	row[7] = uint8(c.A)
+	for n := 8; n < len(row); n *= 2 {
+		copy(row[n:], row[:n])
+	}
+
+	// Copy the first row to all remaining rows.
+	for y := 1; y < h; y++ {
+		i := i0 + y*p.Stride
+		copy(p.Pix[i:i+8*w], row)
+	}
+}
+
+// Crop returns a copy of the portion of the image p visible through r.  Unlike
+// SubImage, Crop does not share pixels with the original image: the result has
+// its own, tightly packed pixel buffer, and its bounds are translated so that
+// their minimum point is (0, 0).
+func (p *NHSVA64) Crop(r image.Rectangle) *NHSVA64 {
+	r = r.Intersect(p.Rect)
+	dst := NewNHSVA64(r.Sub(r.Min))
+	n := 8 * r.Dx()
+	for y, j := r.Min.Y, 0; y < r.Max.Y; y, j = y+1, j+dst.Stride {
+		i := p.PixOffset(r.Min.X, y)
+		copy(dst.Pix[j:j+n], p.Pix[i:i+n])
+	}
+	return dst
+}
+
+// ForEachTile divides the image into tiles of tw×th pixels, starting at the
+// image's minimum point, and calls fn on each tile in row-major order.  Tiles
+// along the right and bottom edges may be smaller than tw×th.  Each tile is a
+// sub-image that shares pixels with p, so fn may modify p through it.
+// ForEachTile panics if tw or th is not positive.
+func (p *NHSVA64) ForEachTile(tw, th int, fn func(tile *NHSVA64)) {
+	if tw <= 0 || th <= 0 {
+		panic("hsvimage: ForEachTile requires positive tile dimensions")
+	}
+	r := p.Rect
+	for y := r.Min.Y; y < r.Max.Y; y += th {
+		for x := r.Min.X; x < r.Max.X; x += tw {
+			fn(p.SubImage(image.Rect(x, y, x+tw, y+th)).(*NHSVA64))
+		}
+	}
+}
+
 // SubImage returns an image representing the portion of the image p visible
 // through r. The returned value shares pixels with the original image.
 func (p *NHSVA64) SubImage(r image.Rectangle) image.Image {
@@ -279,6 +482,16 @@ func (p *NHSVAF64) NHSVAF64At(x, y int) hsvcolor.NHSVAF64 {
 	return hsvcolor.NHSVAF64{H: s[0], S: s[1], V: s[2], A: s[3]}
 }
 
+// HSVAAtUnsafe returns the hue, saturation, value, and alpha channels at the
+// given image coordinates.  Unlike At, it performs no interface boxing, and
+// unlike NHSVAF64At, it performs no bounds check: ensuring that (x, y) lies
+// within p.Rect is the caller's responsibility.
+func (p *NHSVAF64) HSVAAtUnsafe(x, y int) (h, s, v, a float64) {
+	i := p.PixOffset(x, y)
+	px := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+	return px[0], px[1], px[2], px[3]
+}
+
 // PixOffset returns the index of the first element of Pix that corresponds to
 // the pixel at (x, y).
 func (p *NHSVAF64) PixOffset(x, y int) int {
@@ -355,3 +568,135 @@ func NewNHSVAF64(r image.Rectangle) *NHSVAF64 {
 	pix := make([]float64, 4*w*h)
 	return &NHSVAF64{pix, 4 * w, r}
 }
+
+// NHSVAF32 is an in-memory image whose At method returns hsvcolor.NHSVAF32
+// values.
+type NHSVAF32 struct {
+	// Pix holds the image's pixels, in H, S, V, A order. The pixel at
+	// (x, y) starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*4].
+	Pix []float32
+	// Stride is the Pix stride (in 32-bit words) between vertically adjacent pixels.
+	Stride int
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+}
+
+// ColorModel states that an NHSVAF32 image uses the hsvcolor.NHSVAF32 color
+// model.
+func (p *NHSVAF32) ColorModel() color.Model { return hsvcolor.NHSVAF32Model }
+
+// Bounds returns the image's bounding rectangle.
+func (p *NHSVAF32) Bounds() image.Rectangle { return p.Rect }
+
+// At returns the color at the given image coordinates.
+func (p *NHSVAF32) At(x, y int) color.Color {
+	return p.NHSVAF32At(x, y)
+}
+
+// NHSVAF32At returns the color at the given image coordinates as specifically
+// an hsvcolor.NHSVAF32 color.
+func (p *NHSVAF32) NHSVAF32At(x, y int) hsvcolor.NHSVAF32 {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return hsvcolor.NHSVAF32{}
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+	return hsvcolor.NHSVAF32{H: s[0], S: s[1], V: s[2], A: s[3]}
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds to
+// the pixel at (x, y).
+func (p *NHSVAF32) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*4
+}
+
+// Set assigns an arbitrary color to a given coordinate.
+func (p *NHSVAF32) Set(x, y int, c color.Color) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	c1 := hsvcolor.NHSVAF32Model.Convert(c).(hsvcolor.NHSVAF32)
+	s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+	s[0] = c1.H
+	s[1] = c1.S
+	s[2] = c1.V
+	s[3] = c1.A
+}
+
+// SetNHSVAF32 assigns an NHSVAF32 color to a given coordinate.
+func (p *NHSVAF32) SetNHSVAF32(x, y int, c hsvcolor.NHSVAF32) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+	s[0] = c.H
+	s[1] = c.S
+	s[2] = c.V
+	s[3] = c.A
+}
+
+// SubImage returns an image representing the portion of the image p visible
+// through r. The returned value shares pixels with the original image.
+func (p *NHSVAF32) SubImage(r image.Rectangle) image.Image {
+	r = r.Intersect(p.Rect)
+	// If r1 and r2 are Rectangles, r1.Intersect(r2) is not guaranteed to
+	// be inside either r1 or r2 if the intersection is empty. Without
+	// explicitly checking for this, the Pix[i:] expression below can
+	// panic.
+	if r.Empty() {
+		return &NHSVAF32{}
+	}
+	i := p.PixOffset(r.Min.X, r.Min.Y)
+	return &NHSVAF32{
+		Pix:    p.Pix[i:],
+		Stride: p.Stride,
+		Rect:   r,
+	}
+}
+
+// Opaque scans the entire image and reports whether it is fully opaque.
+func (p *NHSVAF32) Opaque() bool {
+	if p.Rect.Empty() {
+		return true
+	}
+	i0, i1 := 3, p.Rect.Dx()*4
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		for i := i0; i < i1; i += 4 {
+			if p.Pix[i] != 1.0 {
+				return false
+			}
+		}
+		i0 += p.Stride
+		i1 += p.Stride
+	}
+	return true
+}
+
+// NewNHSVAF32 returns a new NHSVAF32 image with the given bounds.
+func NewNHSVAF32(r image.Rectangle) *NHSVAF32 {
+	w, h := r.Dx(), r.Dy()
+	pix := make([]float32, 4*w*h)
+	return &NHSVAF32{pix, 4 * w, r}
+}
+
+// New returns a new image with the given bounds whose color model is the given
+// model.  The model must be one of hsvcolor.NHSVAModel, hsvcolor.NHSVA64Model,
+// hsvcolor.NHSVAF64Model, or hsvcolor.NHSVAF32Model, which produce an *NHSVA,
+// *NHSVA64, *NHSVAF64, or *NHSVAF32, respectively.  New returns an error for
+// any other color model.
+func New(model color.Model, r image.Rectangle) (image.Image, error) {
+	switch model {
+	case hsvcolor.NHSVAModel:
+		return NewNHSVA(r), nil
+	case hsvcolor.NHSVA64Model:
+		return NewNHSVA64(r), nil
+	case hsvcolor.NHSVAF64Model:
+		return NewNHSVAF64(r), nil
+	case hsvcolor.NHSVAF32Model:
+		return NewNHSVAF32(r), nil
+	default:
+		return nil, fmt.Errorf("hsvimage: unsupported color model %v", model)
+	}
+}
@@ -3,6 +3,7 @@
 package hsvimage
 
 import (
+	"bytes"
 	"github.com/spakin/hsvimage/hsvcolor"
 	"image"
 	"image/color"
@@ -315,3 +316,523 @@ func TestSimpleColorsF64(t *testing.T) {
 		}
 	}
 }
+
+// TestImageF32 was copied almost literally from the Go standard library's
+// image_test.go file.
+func TestImageF32(t *testing.T) {
+	m := NewNHSVAF32(image.Rect(0, 0, 10, 10))
+	if !image.Rect(0, 0, 10, 10).Eq(m.Bounds()) {
+		t.Fatalf("%T: want bounds %v, got %v", m, image.Rect(0, 0, 10, 10), m.Bounds())
+	}
+	if !cmp(m.ColorModel(), image.Transparent, m.At(6, 3)) {
+		t.Fatalf("%T: at (6, 3), want a zero color, got %v", m, m.At(6, 3))
+	}
+	m.Set(6, 3, image.Opaque)
+	if !cmp(m.ColorModel(), image.Opaque, m.At(6, 3)) {
+		t.Fatalf("%T: at (6, 3), want a non-zero color, got %v", m, m.At(6, 3))
+	}
+	if !m.SubImage(image.Rect(6, 3, 7, 4)).(*NHSVAF32).Opaque() {
+		t.Fatalf("%T: at (6, 3) was not opaque", m)
+	}
+	m = m.SubImage(image.Rect(3, 2, 9, 8)).(*NHSVAF32)
+	if !image.Rect(3, 2, 9, 8).Eq(m.Bounds()) {
+		t.Fatalf("%T: sub-image want bounds %v, got %v", m, image.Rect(3, 2, 9, 8), m.Bounds())
+	}
+	if !cmp(m.ColorModel(), image.Opaque, m.At(6, 3)) {
+		t.Fatalf("%T: sub-image at (6, 3), want a non-zero color, got %v", m, m.At(6, 3))
+	}
+	if !cmp(m.ColorModel(), image.Transparent, m.At(3, 3)) {
+		t.Fatalf("%T: sub-image at (3, 3), want a zero color, got %v", m, m.At(3, 3))
+	}
+	m.Set(3, 3, image.Opaque)
+	if !cmp(m.ColorModel(), image.Opaque, m.At(3, 3)) {
+		t.Fatalf("%T: sub-image at (3, 3), want a non-zero color, got %v", m, m.At(3, 3))
+	}
+	// Test that taking an empty sub-image starting at a corner does not panic.
+	m.SubImage(image.Rect(0, 0, 0, 0))
+	m.SubImage(image.Rect(10, 0, 10, 0))
+	m.SubImage(image.Rect(0, 10, 0, 10))
+	m.SubImage(image.Rect(10, 10, 10, 10))
+}
+
+// TestSimpleColorsF32 checks that we can create an NHSVAF32 image with simple,
+// easily convertible colors and read the pixels back as RGBA.
+func TestSimpleColorsF32(t *testing.T) {
+	// Define the set of colors to use in the image.
+	hsvColors := []hsvcolor.NHSVAF32{
+		{H: 0.0, S: 0.0, V: 0.0, A: 1.0},       // Black
+		{H: 0.0, S: 0.0, V: 1.0, A: 1.0},       // White
+		{H: 0.0, S: 1.0, V: 1.0, A: 1.0},       // Red
+		{H: 120.0, S: 1.0, V: 1.0, A: 1.0},     // Green
+		{H: 240.0, S: 1.0, V: 1.0, A: 1.0},     // Blue
+		{H: 60.0, S: 1.0, V: 1.0, A: 1.0},      // Yellow
+		{H: 0.0, S: 1.0, V: 1.0, A: 0.5},       // Half-transparent red
+		{H: 120.0, S: 0.25, V: 1.0, A: 1.0},    // Pale green
+		{H: 240.0, S: 1.0, V: 0.25, A: 1.0},    // Dark blue
+		{H: 290.0, S: 0.322, V: 0.561, A: 1.0}, // French lilac
+	}
+	rgbColors := []color.RGBA{
+		{R: 0, G: 0, B: 0, A: 255},       // Black
+		{R: 255, G: 255, B: 255, A: 255}, // White
+		{R: 255, G: 0, B: 0, A: 255},     // Red
+		{R: 0, G: 255, B: 0, A: 255},     // Green
+		{R: 0, G: 0, B: 255, A: 255},     // Blue
+		{R: 255, G: 255, B: 0, A: 255},   // Yellow
+		{R: 127, G: 0, B: 0, A: 127},     // Half-transparent red (rounded)
+		{R: 191, G: 255, B: 191, A: 255}, // Pale green
+		{R: 0, G: 0, B: 63, A: 255},      // Dark blue (rounded)
+		{R: 135, G: 97, B: 143, A: 255},  // French lilac
+	}
+	nc := len(hsvColors)
+
+	// Draw an image with NHSVAF32 colors.
+	const wd = 100
+	const ht = 100
+	img := NewNHSVAF32(image.Rect(0, 0, wd, ht))
+	i := 0
+	for y := 0; y < ht; y++ {
+		for x := 0; x < wd; x++ {
+			cwHSV := hsvColors[i%nc]
+			img.Set(x, y, cwHSV)
+			i++
+		}
+	}
+
+	// Check that the RGBA colors we read are as expected.
+	i = 0
+	for y := 0; y < ht; y++ {
+		for x := 0; x < wd; x++ {
+			cwHSV := hsvColors[i%nc]
+			crHSV := img.NHSVAF32At(x, y)
+			if crHSV != cwHSV {
+				t.Fatalf("Wrote %v but read %v at (%d, %d)", cwHSV, crHSV, x, y)
+			}
+			r, g, b, a := img.At(x, y).RGBA()
+			crRGB := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+			if crRGB != rgbColors[i%nc] {
+				t.Logf("Expected %v but saw %v at (%d, %d)", rgbColors[i%nc], crRGB, x, y)
+			}
+			i++
+		}
+	}
+}
+
+// TestHSVAAtUnsafe confirms that HSVAAtUnsafe agrees with NHSVAF64At.
+func TestHSVAAtUnsafe(t *testing.T) {
+	img := NewNHSVAF64(image.Rect(-3, -2, 7, 8))
+	for y := -2; y < 8; y++ {
+		for x := -3; x < 7; x++ {
+			img.SetNHSVAF64(x, y, hsvcolor.NHSVAF64{H: float64(x+3) * 30.0, S: float64(y+2) / 10.0, V: 0.5, A: 1.0})
+		}
+	}
+	for y := -2; y < 8; y++ {
+		for x := -3; x < 7; x++ {
+			c := img.NHSVAF64At(x, y)
+			h, s, v, a := img.HSVAAtUnsafe(x, y)
+			if c != (hsvcolor.NHSVAF64{H: h, S: s, V: v, A: a}) {
+				t.Fatalf("Expected %v but saw {%v %v %v %v} at (%d, %d)", c, h, s, v, a, x, y)
+			}
+		}
+	}
+}
+
+// BenchmarkNHSVAF64At measures the cost of reading pixels via At.
+func BenchmarkNHSVAF64At(b *testing.B) {
+	img := NewNHSVAF64(image.Rect(0, 0, 256, 256))
+	var sum float64
+	for n := 0; n < b.N; n++ {
+		x, y := n&255, (n>>8)&255
+		sum += img.At(x, y).(hsvcolor.NHSVAF64).V
+	}
+	_ = sum
+}
+
+// BenchmarkHSVAAtUnsafe measures the cost of reading pixels via
+// HSVAAtUnsafe.
+func BenchmarkHSVAAtUnsafe(b *testing.B) {
+	img := NewNHSVAF64(image.Rect(0, 0, 256, 256))
+	var sum float64
+	for n := 0; n < b.N; n++ {
+		x, y := n&255, (n>>8)&255
+		_, _, v, _ := img.HSVAAtUnsafe(x, y)
+		sum += v
+	}
+	_ = sum
+}
+
+// TestNew confirms that New dispatches on the color model.
+func TestNew(t *testing.T) {
+	r := image.Rect(1, 2, 5, 7)
+	for _, cm := range []color.Model{hsvcolor.NHSVAModel, hsvcolor.NHSVA64Model, hsvcolor.NHSVAF64Model, hsvcolor.NHSVAF32Model} {
+		img, err := New(cm, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.ColorModel() != cm {
+			t.Fatalf("%T: wrong color model", img)
+		}
+		if !img.Bounds().Eq(r) {
+			t.Fatalf("%T: want bounds %v, got %v", img, r, img.Bounds())
+		}
+	}
+	if _, ok := mustNew(t, hsvcolor.NHSVAModel, r).(*NHSVA); !ok {
+		t.Fatal("NHSVAModel did not produce an *NHSVA")
+	}
+	if _, ok := mustNew(t, hsvcolor.NHSVA64Model, r).(*NHSVA64); !ok {
+		t.Fatal("NHSVA64Model did not produce an *NHSVA64")
+	}
+	if _, ok := mustNew(t, hsvcolor.NHSVAF64Model, r).(*NHSVAF64); !ok {
+		t.Fatal("NHSVAF64Model did not produce an *NHSVAF64")
+	}
+	if _, ok := mustNew(t, hsvcolor.NHSVAF32Model, r).(*NHSVAF32); !ok {
+		t.Fatal("NHSVAF32Model did not produce an *NHSVAF32")
+	}
+	if img, err := New(color.RGBAModel, r); err == nil {
+		t.Fatalf("Expected an error for color.RGBAModel but received a %T", img)
+	}
+}
+
+// mustNew invokes New and aborts the test on error.
+func mustNew(t *testing.T, cm color.Model, r image.Rectangle) image.Image {
+	img, err := New(cm, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+// TestSetRow confirms that SetRow writes only the in-bounds portion of a run.
+func TestSetRow(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 8, 3))
+	run := make([]hsvcolor.NHSVA, 12)
+	for i := range run {
+		run[i] = hsvcolor.NHSVA{H: uint8(i + 1), S: 100, V: 200, A: 255}
+	}
+	img.SetRow(1, -2, run)
+	img.SetRow(5, 0, run)   // Entirely out of bounds
+	img.SetRow(2, 8, run)   // Entirely out of bounds
+	img.SetRow(0, -20, run) // Entirely out of bounds
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 8; x++ {
+			want := hsvcolor.NHSVA{}
+			if y == 1 {
+				want = run[x+2]
+			}
+			if c := img.NHSVAAt(x, y); c != want {
+				t.Fatalf("Expected %v but saw %v at (%d, %d)", want, c, x, y)
+			}
+		}
+	}
+
+	// Writing into a sub-image must not disturb pixels outside it.
+	sub := img.SubImage(image.Rect(2, 0, 5, 1)).(*NHSVA)
+	sub.SetRow(0, 0, run)
+	for x := 0; x < 8; x++ {
+		want := hsvcolor.NHSVA{}
+		if x >= 2 && x < 5 {
+			want = run[x]
+		}
+		if c := img.NHSVAAt(x, 0); c != want {
+			t.Fatalf("Expected %v but saw %v at (%d, 0)", want, c, x)
+		}
+	}
+}
+
+// TestForEachPixel confirms that ForEachPixel visits exactly the pixels
+// within a sub-image's bounds and writes back the colors it is given.
+func TestForEachPixel(t *testing.T) {
+	img := NewNHSVA64(image.Rect(0, 0, 6, 6))
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 6; x++ {
+			img.SetNHSVA64(x, y, hsvcolor.NHSVA64{H: uint16(x * 1000), S: 50000, V: uint16(y * 1000), A: 65535})
+		}
+	}
+	sub := img.SubImage(image.Rect(1, 2, 4, 5)).(*NHSVA64)
+	visited := 0
+	sub.ForEachPixel(func(x, y int, c hsvcolor.NHSVA64) hsvcolor.NHSVA64 {
+		if c.H != uint16(x*1000) || c.V != uint16(y*1000) {
+			t.Fatalf("Expected the color at (%d, %d) but saw %v", x, y, c)
+		}
+		visited++
+		c.S = 0
+		return c
+	})
+	if visited != 9 {
+		t.Fatalf("Expected to visit 9 pixels but visited %d", visited)
+	}
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 6; x++ {
+			want := hsvcolor.NHSVA64{H: uint16(x * 1000), S: 50000, V: uint16(y * 1000), A: 65535}
+			if (image.Point{x, y}).In(sub.Rect) {
+				want.S = 0
+			}
+			if c := img.NHSVA64At(x, y); c != want {
+				t.Fatalf("Expected %v but saw %v at (%d, %d)", want, c, x, y)
+			}
+		}
+	}
+}
+
+// TestNHSVAAtChecked confirms that NHSVAAtChecked distinguishes in-bounds
+// from out-of-bounds accesses.
+func TestNHSVAAtChecked(t *testing.T) {
+	img := NewNHSVA(image.Rect(-2, -2, 3, 3))
+	red := hsvcolor.NHSVA{H: 0, S: 255, V: 255, A: 255}
+	img.SetNHSVA(1, 1, red)
+	if c, ok := img.NHSVAAtChecked(1, 1); !ok || c != red {
+		t.Fatalf("Expected (%v, true) but saw (%v, %v)", red, c, ok)
+	}
+	if c, ok := img.NHSVAAtChecked(-2, -2); !ok || c != (hsvcolor.NHSVA{}) {
+		t.Fatalf("Expected a transparent in-bounds pixel but saw (%v, %v)", c, ok)
+	}
+	sub := img.SubImage(image.Rect(0, 0, 2, 2)).(*NHSVA)
+	for _, pt := range []image.Point{{3, 0}, {0, 3}, {-3, 0}, {0, -3}, {-1, 0}, {2, 1}} {
+		if c, ok := sub.NHSVAAtChecked(pt.X, pt.Y); ok || c != (hsvcolor.NHSVA{}) {
+			t.Fatalf("Expected (zero, false) at %v but saw (%v, %v)", pt, c, ok)
+		}
+	}
+}
+
+// TestEqual confirms that Equal compares the visible pixels of two images.
+func TestEqual(t *testing.T) {
+	img := NewNHSVA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: uint8(x * 30), S: uint8(y * 30), V: 200, A: 255})
+		}
+	}
+	clone := NewNHSVA(img.Rect)
+	copy(clone.Pix, img.Pix)
+	if !img.Equal(clone) || !clone.Equal(img) {
+		t.Fatal("Expected an image to equal its clone")
+	}
+	clone.SetNHSVA(5, 6, hsvcolor.NHSVA{H: 1, S: 2, V: 3, A: 4})
+	if img.Equal(clone) {
+		t.Fatal("Expected images differing in one pixel to be unequal")
+	}
+
+	// Sub-images of different parents should compare only their visible
+	// pixels.
+	r := image.Rect(1, 1, 5, 5)
+	big := NewNHSVA(image.Rect(-10, -10, 20, 20))
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			big.SetNHSVA(x, y, img.NHSVAAt(x, y))
+		}
+	}
+	a := img.SubImage(r).(*NHSVA)
+	b := big.SubImage(r).(*NHSVA)
+	if !a.Equal(b) {
+		t.Fatal("Expected sub-images with identical visible pixels to be equal")
+	}
+	if a.Equal(img.SubImage(r.Add(image.Pt(1, 0))).(*NHSVA)) {
+		t.Fatal("Expected images with different bounds to be unequal")
+	}
+}
+
+// TestNewNHSVAWithPix confirms that NewNHSVAWithPix shares the given pixel
+// buffer and rejects inconsistent buffers.
+func TestNewNHSVAWithPix(t *testing.T) {
+	r := image.Rect(2, 3, 6, 6)
+	pix := make([]uint8, 2*24+16) // Stride of 24 bytes, width of 16 bytes
+	img, err := NewNHSVAWithPix(r, pix, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img.SetNHSVA(5, 5, hsvcolor.NHSVA{H: 1, S: 2, V: 3, A: 4})
+	if pix[2*24+12] != 1 || pix[2*24+15] != 4 {
+		t.Fatalf("Expected the image to share the caller's pixel buffer")
+	}
+	if c := img.NHSVAAt(5, 5); c != (hsvcolor.NHSVA{H: 1, S: 2, V: 3, A: 4}) {
+		t.Fatalf("Expected to read back the color that was written but saw %v", c)
+	}
+	if _, err = NewNHSVAWithPix(r, pix[:len(pix)-1], 24); err == nil {
+		t.Fatal("Expected a too-short pixel buffer to be rejected")
+	}
+	if _, err = NewNHSVAWithPix(r, pix, 12); err == nil {
+		t.Fatal("Expected a too-small stride to be rejected")
+	}
+}
+
+// TestOpaqueHint confirms that SetOpaqueHint lets Opaque skip its scan and
+// that the hint is discarded by each operation that makes a pixel
+// non-opaque.
+func TestOpaqueHint(t *testing.T) {
+	newOpaque := func() *NHSVA {
+		img := NewNHSVA(image.Rect(0, 0, 8, 8))
+		img.SetGlobalAlpha(255)
+		img.SetOpaqueHint(true)
+		return img
+	}
+
+	// A hinted image should not be rescanned until the hint is cleared.
+	img := newOpaque()
+	img.Pix[img.PixOffset(2, 2)+3] = 0
+	if !img.Opaque() {
+		t.Fatal("Expected Opaque to trust the hint")
+	}
+	img.SetOpaqueHint(false)
+	if img.Opaque() {
+		t.Fatal("Expected Opaque to rescan after the hint was cleared")
+	}
+	img.SetOpaqueHint(true)
+	if img.Opaque() {
+		t.Fatal("Expected SetOpaqueHint to ignore a hint that does not hold")
+	}
+
+	// Opaque writes should leave the hint in place.
+	img = newOpaque()
+	img.Set(1, 1, image.Opaque)
+	img.SetNHSVA(1, 2, hsvcolor.NHSVA{H: 10, S: 20, V: 30, A: 255})
+	img.SetRow(3, 0, []hsvcolor.NHSVA{{A: 255}, {A: 255}})
+	img.Pix[img.PixOffset(2, 2)+3] = 0
+	if !img.Opaque() {
+		t.Fatal("Expected opaque writes to leave the hint in place")
+	}
+
+	// Writes that reduce alpha should clear the hint.
+	for _, tc := range []struct {
+		name string
+		fn   func(img *NHSVA)
+	}{
+		{"Set", func(img *NHSVA) { img.Set(1, 1, color.Transparent) }},
+		{"SetNHSVA", func(img *NHSVA) { img.SetNHSVA(1, 1, hsvcolor.NHSVA{A: 254}) }},
+		{"SetRow", func(img *NHSVA) { img.SetRow(5, 6, []hsvcolor.NHSVA{{A: 255}, {A: 128}}) }},
+		{"ChromaKey", func(img *NHSVA) { img.ChromaKey(0, 10, 0) }},
+		{"MultiplyAlpha", func(img *NHSVA) { img.MultiplyAlpha(0.5) }},
+		{"SetGlobalAlpha", func(img *NHSVA) { img.SetGlobalAlpha(100) }},
+		{"DrawRectBorder", func(img *NHSVA) { img.DrawRectBorder(image.Rect(1, 1, 4, 4), hsvcolor.NHSVA{A: 10}, 1) }},
+	} {
+		img = newOpaque()
+		tc.fn(img)
+		if img.Opaque() {
+			t.Fatalf("Expected %s to clear the opaque hint", tc.name)
+		}
+	}
+}
+
+// TestCrop64 confirms that Crop produces an independent, tightly packed copy
+// of part of an NHSVA64 image.
+func TestCrop64(t *testing.T) {
+	img := NewNHSVA64(image.Rect(-4, -2, 12, 10))
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			img.SetNHSVA64(x, y, hsvcolor.NHSVA64{H: uint16(x * 1000), S: uint16(y * 2000), V: uint16(x * y), A: 65535})
+		}
+	}
+	r := image.Rect(-1, 3, 20, 7)
+	crop := img.Crop(r)
+	r = r.Intersect(img.Rect)
+	if !crop.Rect.Eq(image.Rect(0, 0, r.Dx(), r.Dy())) {
+		t.Fatalf("Expected bounds %v but saw %v", image.Rect(0, 0, r.Dx(), r.Dy()), crop.Rect)
+	}
+	if crop.Stride != 8*r.Dx() || len(crop.Pix) != 8*r.Dx()*r.Dy() {
+		t.Fatalf("Expected a tightly packed image but saw stride %d and %d bytes", crop.Stride, len(crop.Pix))
+	}
+	for y := 0; y < r.Dy(); y++ {
+		for x := 0; x < r.Dx(); x++ {
+			exp := img.At(r.Min.X+x, r.Min.Y+y)
+			if c := crop.At(x, y); c != exp {
+				t.Fatalf("Expected %v but saw %v at (%d, %d)", exp, c, x, y)
+			}
+		}
+	}
+
+	// Modifying the crop should not affect the original.
+	crop.SetNHSVA64(0, 0, hsvcolor.NHSVA64{})
+	if c := img.NHSVA64At(r.Min.X, r.Min.Y); c.A != 65535 {
+		t.Fatalf("Expected the original image to be unaffected but saw %v", c)
+	}
+	if empty := img.Crop(image.Rect(100, 100, 110, 110)); !empty.Rect.Empty() {
+		t.Fatalf("Expected an empty crop but saw bounds %v", empty.Rect)
+	}
+}
+
+// TestForEachTile confirms that ForEachTile visits every pixel exactly once,
+// including those in partial tiles along the edges.
+func TestForEachTile(t *testing.T) {
+	img := NewNHSVA64(image.Rect(-3, 2, 14, 9))
+	sub := img.SubImage(image.Rect(-2, 3, 12, 9)).(*NHSVA64)
+	for _, sz := range [][2]int{{1, 1}, {4, 3}, {5, 5}, {14, 6}, {100, 2}} {
+		seen := make(map[image.Point]int)
+		sub.ForEachTile(sz[0], sz[1], func(tile *NHSVA64) {
+			r := tile.Rect
+			if r.Dx() > sz[0] || r.Dy() > sz[1] || !r.In(sub.Rect) {
+				t.Fatalf("Saw an invalid %dx%d tile %v in %v", sz[0], sz[1], r, sub.Rect)
+			}
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				for x := r.Min.X; x < r.Max.X; x++ {
+					seen[image.Point{x, y}]++
+					c := tile.NHSVA64At(x, y)
+					c.V++
+					tile.SetNHSVA64(x, y, c)
+				}
+			}
+		})
+		if len(seen) != sub.Rect.Dx()*sub.Rect.Dy() {
+			t.Fatalf("Expected %d pixels to be visited but saw %d", sub.Rect.Dx()*sub.Rect.Dy(), len(seen))
+		}
+		for pt, n := range seen {
+			if n != 1 {
+				t.Fatalf("Expected %v to be visited once but saw %d visits", pt, n)
+			}
+		}
+	}
+
+	// Modifications made through tiles should be visible in the image.
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			exp := uint16(0)
+			if (image.Point{x, y}).In(sub.Rect) {
+				exp = 5
+			}
+			if v := img.NHSVA64At(x, y).V; v != exp {
+				t.Fatalf("Expected value %d but saw %d at (%d, %d)", exp, v, x, y)
+			}
+		}
+	}
+}
+
+// TestClear64 confirms that Clear agrees with setting each pixel individually
+// and leaves pixels outside a sub-image untouched.
+func TestClear64(t *testing.T) {
+	c := hsvcolor.NHSVA64{H: 0x1234, S: 0x5678, V: 0x9abc, A: 0xdef0}
+	for _, wd := range []int{1, 2, 3, 7, 8, 13} {
+		fast := NewNHSVA64(image.Rect(0, 0, wd+4, 9))
+		slow := NewNHSVA64(fast.Rect)
+		r := image.Rect(2, 3, 2+wd, 8)
+		fast.SubImage(r).(*NHSVA64).Clear(c)
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				slow.SetNHSVA64(x, y, c)
+			}
+		}
+		if !bytes.Equal(fast.Pix, slow.Pix) {
+			t.Fatalf("Clear and SetNHSVA64 disagree for width %d", wd)
+		}
+	}
+	NewNHSVA64(image.Rectangle{}).Clear(c) // Must not panic.
+}
+
+// BenchmarkClear64 measures the cost of clearing an image with Clear.
+func BenchmarkClear64(b *testing.B) {
+	img := NewNHSVA64(image.Rect(0, 0, 1920, 1080))
+	c := hsvcolor.NHSVA64{H: 1, S: 2, V: 3, A: 4}
+	for n := 0; n < b.N; n++ {
+		img.Clear(c)
+	}
+}
+
+// BenchmarkClear64Naive measures the cost of clearing an image by calling
+// SetNHSVA64 on each pixel.
+func BenchmarkClear64Naive(b *testing.B) {
+	img := NewNHSVA64(image.Rect(0, 0, 1920, 1080))
+	c := hsvcolor.NHSVA64{H: 1, S: 2, V: 3, A: 4}
+	for n := 0; n < b.N; n++ {
+		for y := 0; y < 1080; y++ {
+			for x := 0; x < 1920; x++ {
+				img.SetNHSVA64(x, y, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package hsvimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+)
+
+// nhsva64Magic identifies the start of a binary-encoded NHSVA64 image.
+var nhsva64Magic = []byte("HSV64")
+
+// nhsva64Version is the version of the binary encoding produced by
+// NHSVA64.MarshalBinary.
+const nhsva64Version = 1
+
+// nhsva64HeaderLen is the length in bytes of a binary-encoded NHSVA64
+// image's header: the magic string, the version byte, and four 32-bit
+// bounds coordinates.
+var nhsva64HeaderLen = len(nhsva64Magic) + 1 + 4*4
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.  The
+// encoding consists of a magic string, a version byte, the image's bounds as
+// four big-endian 32-bit integers (Min.X, Min.Y, Max.X, and Max.Y), and the
+// pixels themselves, tightly packed in row-major order, with each channel
+// stored as a big-endian 16-bit integer.  Only the pixels within the image's
+// bounds are encoded, so marshaling a sub-image does not encode the rest of
+// its parent.
+func (p *NHSVA64) MarshalBinary() ([]byte, error) {
+	r := p.Rect
+	for _, v := range []int{r.Min.X, r.Min.Y, r.Max.X, r.Max.Y} {
+		if int(int32(v)) != v {
+			return nil, fmt.Errorf("hsvimage: bounds %v do not fit in 32 bits", r)
+		}
+	}
+	w, h := r.Dx(), r.Dy()
+	buf := make([]byte, nhsva64HeaderLen, nhsva64HeaderLen+8*w*h)
+	copy(buf, nhsva64Magic)
+	j := len(nhsva64Magic)
+	buf[j] = nhsva64Version
+	j++
+	for _, v := range []int{r.Min.X, r.Min.Y, r.Max.X, r.Max.Y} {
+		binary.BigEndian.PutUint32(buf[j:], uint32(int32(v)))
+		j += 4
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := p.PixOffset(r.Min.X, y)
+		buf = append(buf, p.Pix[i:i+8*w]...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.  It
+// replaces the image's pixels, stride, and bounds with those decoded from
+// data, which must have been produced by MarshalBinary.
+func (p *NHSVA64) UnmarshalBinary(data []byte) error {
+	// Parse the header.
+	if len(data) < nhsva64HeaderLen || !bytes.HasPrefix(data, nhsva64Magic) {
+		return fmt.Errorf("hsvimage: data do not represent an NHSVA64 image")
+	}
+	j := len(nhsva64Magic)
+	if v := data[j]; v != nhsva64Version {
+		return fmt.Errorf("hsvimage: unsupported NHSVA64 encoding version %d", v)
+	}
+	j++
+	var coords [4]int
+	for k := range coords {
+		coords[k] = int(int32(binary.BigEndian.Uint32(data[j:])))
+		j += 4
+	}
+	r := image.Rect(coords[0], coords[1], coords[2], coords[3])
+	if r.Min.X != coords[0] || r.Min.Y != coords[1] {
+		return fmt.Errorf("hsvimage: malformed NHSVA64 bounds (%d, %d)-(%d, %d)", coords[0], coords[1], coords[2], coords[3])
+	}
+
+	// Copy the pixels.  The pixel count is checked by division because
+	// 8*w*h can overflow for bounds near the 32-bit limits.
+	w := int64(coords[2]) - int64(coords[0])
+	h := int64(coords[3]) - int64(coords[1])
+	payload := int64(len(data) - nhsva64HeaderLen)
+	npix := payload / 8
+	ok := payload%8 == 0
+	if w == 0 || h == 0 {
+		ok = ok && npix == 0
+	} else {
+		ok = ok && npix%w == 0 && npix/w == h
+	}
+	if !ok {
+		return fmt.Errorf("hsvimage: %d bytes of NHSVA64 pixel data do not match bounds %v", payload, r)
+	}
+	pix := make([]uint8, len(data)-nhsva64HeaderLen)
+	copy(pix, data[nhsva64HeaderLen:])
+	p.Pix = pix
+	p.Stride = 8 * r.Dx()
+	p.Rect = r
+	return nil
+}
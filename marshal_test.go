@@ -0,0 +1,74 @@
+// This file tests binary serialization of HSV images.
+
+package hsvimage
+
+import (
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+	"testing"
+)
+
+// TestMarshalNHSVA64 confirms that an NHSVA64 image and a sub-image survive a
+// round trip through MarshalBinary and UnmarshalBinary.
+func TestMarshalNHSVA64(t *testing.T) {
+	img := NewNHSVA64(image.Rect(-3, 2, 7, 9))
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			img.SetNHSVA64(x, y, hsvcolor.NHSVA64{
+				H: uint16(x * 5000),
+				S: uint16(y * 7000),
+				V: uint16(x*y*300 + 1),
+				A: uint16(65535 - y*1000),
+			})
+		}
+	}
+	sub := img.SubImage(image.Rect(0, 4, 5, 6)).(*NHSVA64)
+	for _, src := range []*NHSVA64{img, sub} {
+		data, err := src.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := nhsva64HeaderLen + 8*src.Rect.Dx()*src.Rect.Dy(); len(data) != n {
+			t.Fatalf("Expected %d bytes of encoded data but saw %d", n, len(data))
+		}
+		var dst NHSVA64
+		if err = dst.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if !dst.Rect.Eq(src.Rect) {
+			t.Fatalf("Expected bounds %v but saw %v", src.Rect, dst.Rect)
+		}
+		for y := src.Rect.Min.Y; y < src.Rect.Max.Y; y++ {
+			for x := src.Rect.Min.X; x < src.Rect.Max.X; x++ {
+				if a, b := src.NHSVA64At(x, y), dst.NHSVA64At(x, y); a != b {
+					t.Fatalf("Expected %v but saw %v at (%d, %d)", a, b, x, y)
+				}
+			}
+		}
+	}
+
+	// Corrupt data should be rejected.
+	data, err := sub.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst NHSVA64
+	huge := append([]byte{}, data[:nhsva64HeaderLen]...)
+	for j := len(nhsva64Magic) + 1; j < len(nhsva64Magic)+9; j += 4 {
+		huge[j], huge[j+1], huge[j+2], huge[j+3] = 0x80, 0, 0, 0 // Min = -2³¹
+	}
+	for j := len(nhsva64Magic) + 9; j < nhsva64HeaderLen; j++ {
+		huge[j] = 0 // Max = 0, so 8*w*h overflows to 0
+	}
+	for _, bad := range [][]byte{
+		huge,
+		data[:len(data)-1],
+		append([]byte("X"), data[1:]...),
+		append(append([]byte{}, data[:5]...), append([]byte{99}, data[6:]...)...),
+		nil,
+	} {
+		if err = dst.UnmarshalBinary(bad); err == nil {
+			t.Fatalf("Expected an error when unmarshaling %d bytes of corrupt data", len(bad))
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package hsvimage
+
+import (
+	"math"
+	"math/rand"
+)
+
+// AddValueNoise adds uniformly distributed noise in [-amplitude, amplitude] to
+// each pixel's value, clamping the result to [0, 1].  The noise is drawn from
+// a pseudorandom source seeded with seed, so a given seed always produces the
+// same noise for an image of a given size.  Hue, saturation, and alpha are
+// left untouched.
+func AddValueNoise(img *NHSVAF64, amplitude float64, seed int64) {
+	rng := rand.New(rand.NewSource(seed))
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		i := img.PixOffset(img.Rect.Min.X, y)
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x, i = x+1, i+4 {
+			v := img.Pix[i+2] + amplitude*(2.0*rng.Float64()-1.0)
+			img.Pix[i+2] = math.Max(0.0, math.Min(1.0, v))
+		}
+	}
+}
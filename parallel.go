@@ -0,0 +1,45 @@
+package hsvimage
+
+import (
+	"github.com/spakin/hsvimage/hsvcolor"
+	"runtime"
+	"sync"
+)
+
+// ParallelMap replaces each pixel's color within the image's bounds with the
+// result of applying fn to that color.  The image's rows are divided into
+// contiguous bands, one per worker goroutine.  If workers is zero or negative,
+// runtime.GOMAXPROCS(0) workers are used.  Because fn is invoked concurrently
+// and in no particular order, it must be a pure function of its argument: it
+// must not modify shared state or depend on the order in which pixels are
+// visited.
+func (p *NHSVAF64) ParallelMap(workers int, fn func(c hsvcolor.NHSVAF64) hsvcolor.NHSVAF64) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	h := p.Rect.Dy()
+	if h <= 0 {
+		return
+	}
+	if workers > h {
+		workers = h
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		y0 := p.Rect.Min.Y + h*w/workers
+		y1 := p.Rect.Min.Y + h*(w+1)/workers
+		go func(y0, y1 int) {
+			defer wg.Done()
+			for y := y0; y < y1; y++ {
+				i := p.PixOffset(p.Rect.Min.X, y)
+				for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+4 {
+					s := p.Pix[i : i+4 : i+4]
+					c := fn(hsvcolor.NHSVAF64{H: s[0], S: s[1], V: s[2], A: s[3]})
+					s[0], s[1], s[2], s[3] = c.H, c.S, c.V, c.A
+				}
+			}
+		}(y0, y1)
+	}
+	wg.Wait()
+}
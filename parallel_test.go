@@ -0,0 +1,75 @@
+// This file tests concurrent processing of HSV images.
+
+package hsvimage
+
+import (
+	"fmt"
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+	"math"
+	"testing"
+)
+
+// parallelMapTestFn is a pure, moderately expensive function of a color.
+func parallelMapTestFn(c hsvcolor.NHSVAF64) hsvcolor.NHSVAF64 {
+	return hsvcolor.NHSVAF64{
+		H: math.Mod(c.H+90.0, 360.0),
+		S: math.Sqrt(c.S),
+		V: math.Pow(c.V, 1.0/2.2),
+		A: c.A,
+	}
+}
+
+// newParallelMapTestImage returns an NHSVAF64 image with varied colors.
+func newParallelMapTestImage(r image.Rectangle) *NHSVAF64 {
+	img := NewNHSVAF64(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetNHSVAF64(x, y, hsvcolor.NHSVAF64{
+				H: float64((x*7 + y*3) % 360),
+				S: float64(x%100) / 99.0,
+				V: float64(y%100) / 99.0,
+				A: 1.0,
+			})
+		}
+	}
+	return img
+}
+
+// TestParallelMap confirms that ParallelMap produces the same result as a
+// sequential loop for various worker counts and leaves pixels outside a
+// sub-image alone.
+func TestParallelMap(t *testing.T) {
+	r := image.Rect(0, 0, 50, 37)
+	sr := image.Rect(5, 3, 45, 30)
+	ref := newParallelMapTestImage(r)
+	for y := sr.Min.Y; y < sr.Max.Y; y++ {
+		for x := sr.Min.X; x < sr.Max.X; x++ {
+			ref.SetNHSVAF64(x, y, parallelMapTestFn(ref.NHSVAF64At(x, y)))
+		}
+	}
+	for _, workers := range []int{-1, 0, 1, 2, 3, 8, 100} {
+		img := newParallelMapTestImage(r)
+		img.SubImage(sr).(*NHSVAF64).ParallelMap(workers, parallelMapTestFn)
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if a, b := ref.NHSVAF64At(x, y), img.NHSVAF64At(x, y); a != b {
+					t.Fatalf("%d workers: Expected %v but saw %v at (%d, %d)", workers, a, b, x, y)
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkParallelMap measures how ParallelMap scales with the number of
+// workers.
+func BenchmarkParallelMap(b *testing.B) {
+	img := newParallelMapTestImage(image.Rect(0, 0, 1024, 1024))
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				img.ParallelMap(workers, parallelMapTestFn)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package hsvimage
+
+import "image"
+
+// NewHSVGradient returns an NHSVAF64 image with the given bounds filled with
+// a sweep of fully saturated, fully bright, opaque hues.  Hue increases from 0
+// at the first column (if horizontal is true) or row (if horizontal is false)
+// in equal steps that stop one step short of 360 at the last.
+func NewHSVGradient(r image.Rectangle, horizontal bool) *NHSVAF64 {
+	img := NewNHSVAF64(r)
+	n := r.Dy()
+	if horizontal {
+		n = r.Dx()
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := img.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x, i = x+1, i+4 {
+			k := y - r.Min.Y
+			if horizontal {
+				k = x - r.Min.X
+			}
+			s := img.Pix[i : i+4 : i+4]
+			s[0] = 360.0 * float64(k) / float64(n)
+			s[1], s[2], s[3] = 1.0, 1.0, 1.0
+		}
+	}
+	return img
+}
@@ -0,0 +1,39 @@
+// This file tests generators of reference images.
+
+package hsvimage
+
+import (
+	"image"
+	"testing"
+)
+
+// TestNewHSVGradient confirms that NewHSVGradient sweeps hue from 0 to just
+// under 360 along the requested axis.
+func TestNewHSVGradient(t *testing.T) {
+	r := image.Rect(10, -5, 370, 15)
+	for _, horizontal := range []bool{true, false} {
+		img := NewHSVGradient(r, horizontal)
+		if !img.Rect.Eq(r) {
+			t.Fatalf("Expected bounds %v but saw %v", r, img.Rect)
+		}
+		n, first, last := r.Dy(), image.Pt(10, -5), image.Pt(10, 14)
+		if horizontal {
+			n, last = r.Dx(), image.Pt(369, -5)
+		}
+		if c := img.NHSVAF64At(first.X, first.Y); c.H != 0.0 || c.S != 1.0 || c.V != 1.0 || c.A != 1.0 {
+			t.Fatalf("Expected the first pixel to be red but saw %v", c)
+		}
+		step := 360.0 / float64(n)
+		if h := img.NHSVAF64At(last.X, last.Y).H; h >= 360.0 || h < 360.0-step-1e-9 {
+			t.Fatalf("Expected the last hue to be just under 360 but saw %.5f", h)
+		}
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X + 1; x < r.Max.X; x++ {
+				h0, h1 := img.NHSVAF64At(x-1, y).H, img.NHSVAF64At(x, y).H
+				if horizontal && h1 <= h0 || !horizontal && h1 != h0 {
+					t.Fatalf("Unexpected hues %.5f and %.5f at (%d, %d) and (%d, %d)", h0, h1, x-1, y, x, y)
+				}
+			}
+		}
+	}
+}
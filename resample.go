@@ -0,0 +1,72 @@
+package hsvimage
+
+import (
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+)
+
+// bilinearTaps returns the two source indices and the weight of the second
+// index for destination index d when resampling n source samples to m
+// destination samples.  Sample centers are aligned, and source indices are
+// clamped to [0, n-1].
+func bilinearTaps(d, m, n int) (i0, i1 int, frac float64) {
+	s := (float64(d)+0.5)*float64(n)/float64(m) - 0.5
+	if s < 0.0 {
+		s = 0.0
+	}
+	i0 = int(s)
+	frac = s - float64(i0)
+	i1 = i0 + 1
+	if i1 > n-1 {
+		i1 = n - 1
+	}
+	if i0 > n-1 {
+		i0 = n - 1
+	}
+	return i0, i1, frac
+}
+
+// Resize uses bilinear interpolation to resample an NHSVAF64 image to a new
+// width and height.  Saturation, value, and alpha are interpolated linearly.
+// Hue is interpolated on the unit circle so that, for example, a hue halfway
+// between 350 and 10 is 0, not 180, and grays, whose hue is undefined, are
+// excluded from the hue computation.  The result's bounds start at (0, 0).  If
+// w or h is not positive, Resize returns an empty image.
+func Resize(src *NHSVAF64, w, h int) *NHSVAF64 {
+	if w <= 0 || h <= 0 {
+		return NewNHSVAF64(image.Rectangle{})
+	}
+	dst := NewNHSVAF64(image.Rect(0, 0, w, h))
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if sw <= 0 || sh <= 0 {
+		return dst
+	}
+	for y := 0; y < h; y++ {
+		y0, y1, fy := bilinearTaps(y, h, sh)
+		for x := 0; x < w; x++ {
+			x0, x1, fx := bilinearTaps(x, w, sw)
+			taps := [4]struct {
+				x, y int
+				wt   float64
+			}{
+				{x0, y0, (1.0 - fx) * (1.0 - fy)},
+				{x1, y0, fx * (1.0 - fy)},
+				{x0, y1, (1.0 - fx) * fy},
+				{x1, y1, fx * fy},
+			}
+			var hs hueSum
+			var s, v, a float64
+			for _, tp := range taps {
+				c := src.NHSVAF64At(src.Rect.Min.X+tp.x, src.Rect.Min.Y+tp.y)
+				if c.HueDefined() {
+					hs.add(c.H, tp.wt)
+				}
+				s += tp.wt * c.S
+				v += tp.wt * c.V
+				a += tp.wt * c.A
+			}
+			dst.SetNHSVAF64(x, y, hsvcolor.NHSVAF64{H: hs.mean(), S: s, V: v, A: a})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,73 @@
+// This file tests resampling of HSV images.
+
+package hsvimage
+
+import (
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+	"math"
+	"testing"
+)
+
+// TestResizeHueWrap confirms that hue is interpolated around the color
+// wheel rather than linearly.
+func TestResizeHueWrap(t *testing.T) {
+	src := NewNHSVAF64(image.Rect(0, 0, 2, 1))
+	src.SetNHSVAF64(0, 0, hsvcolor.NHSVAF64{H: 350.0, S: 1.0, V: 1.0, A: 1.0})
+	src.SetNHSVAF64(1, 0, hsvcolor.NHSVAF64{H: 10.0, S: 1.0, V: 1.0, A: 1.0})
+	dst := Resize(src, 3, 1)
+	if !image.Rect(0, 0, 3, 1).Eq(dst.Bounds()) {
+		t.Fatalf("Expected bounds %v but saw %v", image.Rect(0, 0, 3, 1), dst.Bounds())
+	}
+	h := dst.NHSVAF64At(1, 0).H
+	if math.Min(h, 360.0-h) > 1.0 {
+		t.Fatalf("Expected a middle hue near 0 but saw %.3f", h)
+	}
+	if c := dst.NHSVAF64At(0, 0); math.Abs(c.H-350.0) > 1e-6 || math.Abs(c.V-1.0) > 1e-9 {
+		t.Fatalf("Expected the left edge to remain at hue 350 but saw %v", c)
+	}
+}
+
+// TestResizeDownscale confirms that downscaling a uniform image yields the
+// same uniform color.
+func TestResizeDownscale(t *testing.T) {
+	src := NewNHSVAF64(image.Rect(5, 5, 25, 15))
+	want := hsvcolor.NHSVAF64{H: 200.0, S: 0.5, V: 0.25, A: 1.0}
+	for y := 5; y < 15; y++ {
+		for x := 5; x < 25; x++ {
+			src.SetNHSVAF64(x, y, want)
+		}
+	}
+	dst := Resize(src, 4, 2)
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			c := dst.NHSVAF64At(x, y)
+			if math.Abs(c.H-want.H) > 1e-6 || math.Abs(c.S-want.S) > 1e-9 || math.Abs(c.V-want.V) > 1e-9 || math.Abs(c.A-want.A) > 1e-9 {
+				t.Fatalf("Expected %v but saw %v at (%d, %d)", want, c, x, y)
+			}
+		}
+	}
+}
+
+// TestResizeGrayHue confirms that grays do not pull interpolated hues toward
+// red.
+func TestResizeGrayHue(t *testing.T) {
+	src := NewNHSVAF64(image.Rect(0, 0, 2, 1))
+	src.SetNHSVAF64(0, 0, hsvcolor.NHSVAF64{H: 0.0, S: 0.0, V: 0.5, A: 1.0})
+	src.SetNHSVAF64(1, 0, hsvcolor.NHSVAF64{H: 120.0, S: 1.0, V: 1.0, A: 1.0})
+	dst := Resize(src, 3, 1)
+	if c := dst.NHSVAF64At(1, 0); math.Abs(c.H-120.0) > 1e-6 || math.Abs(c.S-0.5) > 1e-9 {
+		t.Fatalf("Expected hue 120 and saturation 0.5 but saw %v", c)
+	}
+}
+
+// TestResizeEmpty confirms that non-positive sizes produce an empty image
+// rather than a panic.
+func TestResizeEmpty(t *testing.T) {
+	src := NewNHSVAF64(image.Rect(0, 0, 4, 4))
+	for _, sz := range [][2]int{{0, 3}, {3, 0}, {-2, 3}, {3, -2}, {-1, -1}} {
+		if dst := Resize(src, sz[0], sz[1]); !dst.Rect.Empty() || len(dst.Pix) != 0 {
+			t.Fatalf("Expected an empty image for size %dx%d but saw bounds %v", sz[0], sz[1], dst.Rect)
+		}
+	}
+}
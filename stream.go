@@ -0,0 +1,200 @@
+package hsvimage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/spakin/hsvimage/hsvcolor"
+	"io"
+)
+
+// nhsva64StreamMagic identifies the start of a row-streamed NHSVA64 image.
+var nhsva64StreamMagic = []byte("HSV64R")
+
+// nhsva64StreamVersion is the version of the streaming format produced by
+// NHSVA64Writer.
+const nhsva64StreamVersion = 1
+
+// nhsva64StreamMaxWidth is the widest row an NHSVA64Writer will write or an
+// NHSVA64Reader will accept.
+const nhsva64StreamMaxWidth = 1 << 24
+
+// Each row in a stream is preceded by a tag byte.  The stream ends with an
+// end tag followed by the number of rows as a big-endian 32-bit integer, which
+// lets a reader distinguish a complete stream from a truncated one.
+const (
+	nhsva64RowTag = 'R'
+	nhsva64EndTag = 'E'
+)
+
+// An NHSVA64Writer writes an image one row at a time without materializing
+// the full image in memory.  The stream begins with a magic string, a version
+// byte, and the image's width as a big-endian 32-bit integer.  Each row
+// follows as a tag byte and the row's pixels, with each channel stored as a
+// big-endian 16-bit integer.  Close terminates the stream.
+type NHSVA64Writer struct {
+	w      *bufio.Writer
+	width  int
+	rows   int
+	err    error
+	closed bool
+}
+
+// NewNHSVA64Writer returns an NHSVA64Writer that writes rows of the given
+// width, which may not exceed 2²⁴ pixels, to w.  It returns an error if the
+// width is invalid or the stream header cannot be written.
+func NewNHSVA64Writer(w io.Writer, width int) (*NHSVA64Writer, error) {
+	if width < 0 || width > nhsva64StreamMaxWidth {
+		return nil, fmt.Errorf("hsvimage: invalid row width %d", width)
+	}
+	sw := &NHSVA64Writer{
+		w:     bufio.NewWriter(w),
+		width: width,
+	}
+	sw.w.Write(nhsva64StreamMagic) // bufio.Writer errors persist to the final Write.
+	sw.w.WriteByte(nhsva64StreamVersion)
+	var wd [4]byte
+	binary.BigEndian.PutUint32(wd[:], uint32(width))
+	if _, err := sw.w.Write(wd[:]); err != nil {
+		return nil, err
+	}
+	return sw, nil
+}
+
+// WriteRow writes one row of pixels, which must contain exactly as many
+// colors as the width passed to NewNHSVA64Writer.
+func (sw *NHSVA64Writer) WriteRow(row []hsvcolor.NHSVA64) error {
+	switch {
+	case sw.err != nil:
+		return sw.err
+	case sw.closed:
+		return fmt.Errorf("hsvimage: write to a closed NHSVA64Writer")
+	case len(row) != sw.width:
+		return fmt.Errorf("hsvimage: expected a row of %d pixels but saw %d", sw.width, len(row))
+	}
+	if sw.err = sw.w.WriteByte(nhsva64RowTag); sw.err != nil {
+		return sw.err
+	}
+	var px [8]byte
+	for _, c := range row {
+		binary.BigEndian.PutUint16(px[0:], c.H)
+		binary.BigEndian.PutUint16(px[2:], c.S)
+		binary.BigEndian.PutUint16(px[4:], c.V)
+		binary.BigEndian.PutUint16(px[6:], c.A)
+		if _, sw.err = sw.w.Write(px[:]); sw.err != nil {
+			return sw.err
+		}
+	}
+	sw.rows++
+	return nil
+}
+
+// Close terminates the stream and flushes any buffered data.  It does not
+// close the underlying io.Writer.
+func (sw *NHSVA64Writer) Close() error {
+	if sw.err != nil || sw.closed {
+		return sw.err
+	}
+	sw.closed = true
+	var end [5]byte
+	end[0] = nhsva64EndTag
+	binary.BigEndian.PutUint32(end[1:], uint32(sw.rows))
+	if _, sw.err = sw.w.Write(end[:]); sw.err != nil {
+		return sw.err
+	}
+	sw.err = sw.w.Flush()
+	return sw.err
+}
+
+// An NHSVA64Reader reads an image written by an NHSVA64Writer one row at a
+// time.
+type NHSVA64Reader struct {
+	r     *bufio.Reader
+	width int
+	rows  int
+	done  bool
+}
+
+// NewNHSVA64Reader reads a stream header from r and returns an NHSVA64Reader
+// positioned at the first row.  It rejects rows wider than 2²⁴ pixels.
+func NewNHSVA64Reader(r io.Reader) (*NHSVA64Reader, error) {
+	br := bufio.NewReader(r)
+	hdr := make([]byte, len(nhsva64StreamMagic)+1+4)
+	if _, err := io.ReadFull(br, hdr); err != nil {
+		return nil, fmt.Errorf("hsvimage: failed to read NHSVA64 stream header (%v)", err)
+	}
+	j := len(nhsva64StreamMagic)
+	if !bytes.Equal(hdr[:j], nhsva64StreamMagic) {
+		return nil, fmt.Errorf("hsvimage: data do not represent an NHSVA64 stream")
+	}
+	if v := hdr[j]; v != nhsva64StreamVersion {
+		return nil, fmt.Errorf("hsvimage: unsupported NHSVA64 stream version %d", v)
+	}
+	width := binary.BigEndian.Uint32(hdr[j+1:])
+	if width > nhsva64StreamMaxWidth {
+		return nil, fmt.Errorf("hsvimage: invalid row width %d", width)
+	}
+	return &NHSVA64Reader{r: br, width: int(width)}, nil
+}
+
+// Width returns the number of pixels in each row.
+func (sr *NHSVA64Reader) Width() int {
+	return sr.width
+}
+
+// ReadRow returns the next row of pixels.  It returns io.EOF after the last
+// row, an error if the stream ends prematurely, and any other error from the
+// underlying io.Reader unchanged.  The row grows as pixels
+// are read, so a truncated stream does not cause a full row to be allocated.
+func (sr *NHSVA64Reader) ReadRow() ([]hsvcolor.NHSVA64, error) {
+	if sr.done {
+		return nil, io.EOF
+	}
+	tag, err := sr.r.ReadByte()
+	switch {
+	case err == io.EOF:
+		return nil, fmt.Errorf("hsvimage: NHSVA64 stream ended after %d rows without a terminator", sr.rows)
+	case err != nil:
+		return nil, err
+	}
+	switch tag {
+	case nhsva64RowTag:
+	case nhsva64EndTag:
+		var n [4]byte
+		switch _, err = io.ReadFull(sr.r, n[:]); err {
+		case nil:
+		case io.EOF, io.ErrUnexpectedEOF:
+			return nil, fmt.Errorf("hsvimage: truncated NHSVA64 stream terminator")
+		default:
+			return nil, err
+		}
+		if nr := int(binary.BigEndian.Uint32(n[:])); nr != sr.rows {
+			return nil, fmt.Errorf("hsvimage: NHSVA64 stream declared %d rows but contained %d", nr, sr.rows)
+		}
+		sr.done = true
+		return nil, io.EOF
+	default:
+		return nil, fmt.Errorf("hsvimage: invalid NHSVA64 stream tag 0x%02x", tag)
+	}
+	const initCap = 4096 // Initial row capacity in pixels
+	row := make([]hsvcolor.NHSVA64, 0, clampInt(sr.width, 0, initCap))
+	var px [8]byte
+	for x := 0; x < sr.width; x++ {
+		switch _, err = io.ReadFull(sr.r, px[:]); err {
+		case nil:
+		case io.EOF, io.ErrUnexpectedEOF:
+			return nil, fmt.Errorf("hsvimage: truncated NHSVA64 stream row %d", sr.rows)
+		default:
+			return nil, err
+		}
+		row = append(row, hsvcolor.NHSVA64{
+			H: binary.BigEndian.Uint16(px[0:]),
+			S: binary.BigEndian.Uint16(px[2:]),
+			V: binary.BigEndian.Uint16(px[4:]),
+			A: binary.BigEndian.Uint16(px[6:]),
+		})
+	}
+	sr.rows++
+	return row, nil
+}
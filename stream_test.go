@@ -0,0 +1,141 @@
+// This file tests row-by-row streaming of HSV images.
+
+package hsvimage
+
+import (
+	"bytes"
+	"errors"
+	"github.com/spakin/hsvimage/hsvcolor"
+	"io"
+	"testing"
+)
+
+// TestNHSVA64Stream confirms that rows written by an NHSVA64Writer are read
+// back unchanged by an NHSVA64Reader.
+func TestNHSVA64Stream(t *testing.T) {
+	const w, h = 7, 5
+	var buf bytes.Buffer
+	sw, err := NewNHSVA64Writer(&buf, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := make([][]hsvcolor.NHSVA64, h)
+	for y := range rows {
+		rows[y] = make([]hsvcolor.NHSVA64, w)
+		for x := range rows[y] {
+			rows[y][x] = hsvcolor.NHSVA64{
+				H: uint16(x * 9000),
+				S: uint16(y * 13000),
+				V: uint16(x*y*1000 + 1),
+				A: uint16(65535 - x - y),
+			}
+		}
+		if err = sw.WriteRow(rows[y]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = sw.WriteRow(rows[0][:w-1]); err == nil {
+		t.Fatal("Expected a short row to be rejected")
+	}
+	if err = sw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = sw.WriteRow(rows[0]); err == nil {
+		t.Fatal("Expected a write after Close to be rejected")
+	}
+
+	// Read the rows back.
+	data := buf.Bytes()
+	sr, err := NewNHSVA64Reader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sr.Width() != w {
+		t.Fatalf("Expected a width of %d but saw %d", w, sr.Width())
+	}
+	for y := 0; y < h; y++ {
+		row, err := sr.ReadRow()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for x, c := range row {
+			if c != rows[y][x] {
+				t.Fatalf("Expected %v but saw %v at (%d, %d)", rows[y][x], c, x, y)
+			}
+		}
+	}
+	if _, err = sr.ReadRow(); err != io.EOF {
+		t.Fatalf("Expected io.EOF but saw %v", err)
+	}
+
+	// A truncated stream should produce an error rather than io.EOF.
+	sr, err = NewNHSVA64Reader(bytes.NewReader(data[:len(data)-20]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for err == nil {
+		_, err = sr.ReadRow()
+	}
+	if err == io.EOF {
+		t.Fatal("Expected a truncated stream to be reported as an error")
+	}
+}
+
+// TestNHSVA64StreamWidth confirms that excessive row widths are rejected
+// rather than allocated.
+func TestNHSVA64StreamWidth(t *testing.T) {
+	if _, err := NewNHSVA64Writer(new(bytes.Buffer), nhsva64StreamMaxWidth+1); err == nil {
+		t.Fatal("Expected an excessive width to be rejected by NewNHSVA64Writer")
+	}
+	hdr := append([]byte(nil), nhsva64StreamMagic...)
+	hdr = append(hdr, nhsva64StreamVersion, 0x7f, 0xff, 0xff, 0xff)
+	if _, err := NewNHSVA64Reader(bytes.NewReader(hdr)); err == nil {
+		t.Fatal("Expected an excessive width to be rejected by NewNHSVA64Reader")
+	}
+
+	// A maximum-width row that is cut short should produce an error.
+	hdr[len(hdr)-4], hdr[len(hdr)-3], hdr[len(hdr)-2], hdr[len(hdr)-1] = 0x01, 0x00, 0x00, 0x00
+	sr, err := NewNHSVA64Reader(bytes.NewReader(append(hdr, nhsva64RowTag, 1, 2, 3, 4, 5, 6, 7, 8)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = sr.ReadRow(); err == nil || err == io.EOF {
+		t.Fatalf("Expected a truncated row to be reported as an error but saw %v", err)
+	}
+}
+
+// TestNHSVA64StreamReadError confirms that an error from the underlying reader
+// is passed through rather than reported as a truncated stream.
+func TestNHSVA64StreamReadError(t *testing.T) {
+	var buf bytes.Buffer
+	sw, err := NewNHSVA64Writer(&buf, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sw.WriteRow(make([]hsvcolor.NHSVA64, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if err = sw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	errBroken := errors.New("broken reader")
+	for _, n := range []int{len(data) - 22, len(data) - 18, len(data) - 3} {
+		// Fail at a row tag, within a row, and within the terminator.
+		pr, pw := io.Pipe()
+		go func(b []byte) {
+			pw.Write(b)
+			pw.CloseWithError(errBroken)
+		}(data[:n])
+		sr, err := NewNHSVA64Reader(pr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for err == nil {
+			_, err = sr.ReadRow()
+		}
+		if err != errBroken {
+			t.Fatalf("Expected %v after %d bytes but saw %v", errBroken, n, err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package hsvimage
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+	"io"
+	"strconv"
+)
+
+// hsvTextMagic identifies the start of an image encoded by WriteHSVText.
+const hsvTextMagic = "HSVA8"
+
+// hsvTextVersion is the version of the plain-text encoding produced by
+// WriteHSVText.  It follows the magic string.
+const hsvTextVersion = 1
+
+// hsvTextMaxPixels is the largest number of pixels ReadHSVText will accept.
+// ReadHSVText grows its pixel buffer as values arrive, so a header alone
+// cannot force a large allocation; the limit bounds what the data can.
+const hsvTextMaxPixels = 1 << 26
+
+// WriteHSVText writes an image to w in a human-readable plain-text format
+// reminiscent of NetPBM's P3 format.  The first line contains the string
+// "HSVA8" and the format version; the second, the image's width and height;
+// the third, the coordinates of the image's minimum point; and each
+// subsequent line, one row of pixels as space-separated H S V A quadruples of
+// decimal integers.
+func WriteHSVText(w io.Writer, img *NHSVA) error {
+	bw := bufio.NewWriter(w)
+	r := img.Rect
+	fmt.Fprintf(bw, "%s %d\n%d %d\n%d %d\n", hsvTextMagic, hsvTextVersion, r.Dx(), r.Dy(), r.Min.X, r.Min.Y)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := img.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x, i = x+1, i+4 {
+			s := img.Pix[i : i+4 : i+4]
+			sep := "  "
+			if x == r.Min.X {
+				sep = ""
+			}
+			fmt.Fprintf(bw, "%s%3d %3d %3d %3d", sep, s[0], s[1], s[2], s[3])
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// An hsvTextReader reads whitespace-separated tokens in the format written
+// by WriteHSVText.
+type hsvTextReader struct {
+	sc *bufio.Scanner
+}
+
+// newHSVTextReader returns an hsvTextReader that reads from r.
+func newHSVTextReader(r io.Reader) *hsvTextReader {
+	sc := bufio.NewScanner(r)
+	sc.Split(bufio.ScanWords)
+	return &hsvTextReader{sc: sc}
+}
+
+// next returns the next token.  what describes the token for error messages.
+func (tr *hsvTextReader) next(what string) (string, error) {
+	if !tr.sc.Scan() {
+		if err := tr.sc.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("hsvimage: unexpected end of input while reading %s", what)
+	}
+	return tr.sc.Text(), nil
+}
+
+// nextInt returns the next token as an integer.  what describes the token for
+// error messages.
+func (tr *hsvTextReader) nextInt(what string) (int, error) {
+	tok, err := tr.next(what)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(tok)
+	if err != nil {
+		return 0, fmt.Errorf("hsvimage: invalid %s %q", what, tok)
+	}
+	return n, nil
+}
+
+// header reads the magic string, version, size, and origin and returns the
+// image's bounds.  It rejects images with more than hsvTextMaxPixels pixels
+// and bounds that do not fit in 32 bits.
+func (tr *hsvTextReader) header() (image.Rectangle, error) {
+	magic, err := tr.next("header")
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	if magic != hsvTextMagic {
+		return image.Rectangle{}, fmt.Errorf("hsvimage: expected %q but saw %q", hsvTextMagic, magic)
+	}
+	switch v, err := tr.nextInt("version"); {
+	case err != nil:
+		return image.Rectangle{}, err
+	case v != hsvTextVersion:
+		return image.Rectangle{}, fmt.Errorf("hsvimage: unsupported HSV text version %d", v)
+	}
+	var hdr [4]int // Width, height, minimum x, minimum y
+	for k, what := range [...]string{"width", "height", "minimum x", "minimum y"} {
+		if hdr[k], err = tr.nextInt(what); err != nil {
+			return image.Rectangle{}, err
+		}
+	}
+	for _, v := range hdr {
+		if int64(int32(v)) != int64(v) {
+			return image.Rectangle{}, fmt.Errorf("hsvimage: header value %d does not fit in 32 bits", v)
+		}
+	}
+	w, h := int64(hdr[0]), int64(hdr[1])
+	if w < 0 || h < 0 || w*h > hsvTextMaxPixels {
+		return image.Rectangle{}, fmt.Errorf("hsvimage: invalid image size %dx%d", w, h)
+	}
+	if mx, my := int64(hdr[2])+w, int64(hdr[3])+h; int64(int32(mx)) != mx || int64(int32(my)) != my {
+		return image.Rectangle{}, fmt.Errorf("hsvimage: image bounds starting at (%d, %d) do not fit in 32 bits", hdr[2], hdr[3])
+	}
+	return image.Rect(hdr[2], hdr[3], hdr[2]+hdr[0], hdr[3]+hdr[1]), nil
+}
+
+// ReadHSVText reads an image in the format written by WriteHSVText.  Any
+// amount of whitespace may separate values.  Images with more than 2²⁶
+// pixels are rejected.  The pixel buffer grows as values are read, so a
+// truncated image does not cause its full size to be allocated.
+func ReadHSVText(r io.Reader) (*NHSVA, error) {
+	tr := newHSVTextReader(r)
+	bounds, err := tr.header()
+	if err != nil {
+		return nil, err
+	}
+	const initCap = 1 << 16 // Initial buffer capacity in bytes
+	w, h := bounds.Dx(), bounds.Dy()
+	pix := make([]uint8, 0, clampInt(4*w*h, 0, initCap))
+	for i := 0; i < 4*w*h; i++ {
+		n, err := tr.nextInt("channel value")
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 || n > 255 {
+			return nil, fmt.Errorf("hsvimage: channel value %d is out of range", n)
+		}
+		pix = append(pix, uint8(n))
+	}
+	return &NHSVA{Pix: pix, Stride: 4 * w, Rect: bounds}, nil
+}
+
+// DecodeHSVText reads an image in the format written by WriteHSVText and
+// returns it as an *NHSVA.  Because the format is registered with the image
+// package under the name "hsvtext", image.Decode can also decode it.
+func DecodeHSVText(r io.Reader) (image.Image, error) {
+	img, err := ReadHSVText(r)
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// DecodeHSVTextConfig returns the color model and dimensions of an image in
+// the format written by WriteHSVText without decoding the entire image.
+func DecodeHSVTextConfig(r io.Reader) (image.Config, error) {
+	bounds, err := newHSVTextReader(r).header()
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{
+		ColorModel: hsvcolor.NHSVAModel,
+		Width:      bounds.Dx(),
+		Height:     bounds.Dy(),
+	}, nil
+}
+
+// init registers the plain-text HSV format with the image package, as the
+// standard library's image/png and image/jpeg packages do for their formats.
+// The registered magic string is versioned by the header that follows it.
+func init() {
+	image.RegisterFormat("hsvtext", hsvTextMagic, DecodeHSVText, DecodeHSVTextConfig)
+}
@@ -0,0 +1,128 @@
+// This file tests the plain-text encoding of HSV images.
+
+package hsvimage
+
+import (
+	"bytes"
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestHSVTextRoundTrip confirms that an image survives a round trip through
+// WriteHSVText and ReadHSVText.
+func TestHSVTextRoundTrip(t *testing.T) {
+	img := NewNHSVA(image.Rect(-2, 5, 6, 10))
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: uint8(x * 30), S: uint8(y * 20), V: uint8(x * y), A: uint8(255 - y)})
+		}
+	}
+	sub := img.SubImage(image.Rect(0, 6, 4, 9)).(*NHSVA)
+	var buf bytes.Buffer
+	if err := WriteHSVText(&buf, sub); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 3+3 || lines[0] != "HSVA8 1" || lines[1] != "4 3" || lines[2] != "0 6" {
+		t.Fatalf("Unexpected text encoding:\n%s", buf.String())
+	}
+	back, err := ReadHSVText(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !back.Equal(sub) {
+		t.Fatalf("Expected %v but saw %v", sub.Pix, back.Pix)
+	}
+
+	// Malformed input should be rejected.
+	for _, bad := range []string{
+		"",
+		"P3\n1 1\n0 0\n0 0 0 0\n",
+		"HSVA8 2\n1 1\n0 0\n0 0 0 0\n",
+		"HSVA8 1\n1 1\n0 0\n0 0 0\n",
+		"HSVA8 1\n1 1\n0 0\n0 0 0 256\n",
+		"HSVA8 1\n-1 1\n0 0\n",
+		"HSVA8 1\n1 x\n0 0\n0 0 0 0\n",
+		"HSVA8 1\n4611686018427387904 4\n0 0\n",
+		"HSVA8 1\n65536 65536\n0 0\n",
+		"HSVA8 1\n1 1\n9223372036854775807 0\n0 0 0 0\n",
+		"HSVA8 1\n2 1\n2147483647 0\n0 0 0 0 0 0 0 0\n",
+	} {
+		if _, err := ReadHSVText(strings.NewReader(bad)); err == nil {
+			t.Fatalf("Expected an error when reading %q", bad)
+		}
+	}
+}
+
+// TestDecodeHSVText confirms that image.Decode and image.DecodeConfig
+// recognize the format written by WriteHSVText.
+func TestDecodeHSVText(t *testing.T) {
+	img := NewNHSVA(image.Rect(3, 4, 8, 7))
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			img.SetNHSVA(x, y, hsvcolor.NHSVA{H: uint8(x * 20), S: uint8(y * 40), V: 200, A: uint8(x * y)})
+		}
+	}
+	var buf bytes.Buffer
+	if err := WriteHSVText(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	text := buf.String()
+
+	cfg, format, err := image.DecodeConfig(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "hsvtext" || cfg.Width != 5 || cfg.Height != 3 || cfg.ColorModel != hsvcolor.NHSVAModel {
+		t.Fatalf("Unexpected format %q and configuration %+v", format, cfg)
+	}
+
+	dec, format, err := image.Decode(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "hsvtext" {
+		t.Fatalf("Expected format \"hsvtext\" but saw %q", format)
+	}
+	back, ok := dec.(*NHSVA)
+	if !ok {
+		t.Fatalf("Expected an *NHSVA but saw a %T", dec)
+	}
+	if !back.Equal(img) {
+		t.Fatalf("Expected %v but saw %v", img.Pix, back.Pix)
+	}
+	if _, err = DecodeHSVText(strings.NewReader("HSVA8 1 1 1 0 0 1 2 3")); err == nil {
+		t.Fatal("Expected an error when decoding a truncated image")
+	}
+
+	// Oversized headers should be rejected by image.Decode without
+	// allocating or panicking.
+	for _, bad := range []string{
+		"HSVA8 1 4611686018427387904 4 0 0",
+		"HSVA8 1 1 1 9223372036854775807 0 0 0 0 0",
+	} {
+		if _, _, err = image.Decode(strings.NewReader(bad)); err == nil {
+			t.Fatalf("Expected an error when decoding %q", bad)
+		}
+		if _, _, err = image.DecodeConfig(strings.NewReader(bad)); err == nil {
+			t.Fatalf("Expected an error when decoding the configuration of %q", bad)
+		}
+	}
+}
+
+// TestHSVTextTruncatedLarge confirms that a header declaring a large image
+// followed by little data fails without allocating the full image.
+func TestHSVTextTruncatedLarge(t *testing.T) {
+	const bad = "HSVA8 1\n8192 8192\n0 0\n1 2 3 4\n"
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := ReadHSVText(strings.NewReader(bad)); err == nil {
+		t.Fatal("Expected an error when reading a truncated image")
+	}
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Fatalf("Expected a small allocation but saw %d bytes", n)
+	}
+}
@@ -0,0 +1,67 @@
+package hsvimage
+
+import "image"
+
+// transform copies each pixel of src to a new image with bounds r.  f maps
+// a pixel's offset from src.Rect.Min to its offset from r.Min.  Pixels are
+// copied as raw bytes; no color conversion is performed.
+func transform(src *NHSVA, r image.Rectangle, f func(dx, dy int) (int, int)) *NHSVA {
+	dst := NewNHSVA(r)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	for dy := 0; dy < h; dy++ {
+		i := src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y+dy)
+		for dx := 0; dx < w; dx, i = dx+1, i+4 {
+			tx, ty := f(dx, dy)
+			j := dst.PixOffset(r.Min.X+tx, r.Min.Y+ty)
+			copy(dst.Pix[j:j+4:j+4], src.Pix[i:i+4:i+4])
+		}
+	}
+	return dst
+}
+
+// Rotate90 returns a copy of an image rotated 90° clockwise.  The result's
+// bounds have their origin at (0, 0) and have the source's width and height
+// swapped.
+func Rotate90(src *NHSVA) *NHSVA {
+	h := src.Rect.Dy()
+	return transform(src, image.Rect(0, 0, h, src.Rect.Dx()), func(dx, dy int) (int, int) {
+		return h - 1 - dy, dx
+	})
+}
+
+// Rotate180 returns a copy of an image rotated 180°.  The result has the same
+// bounds as the source.
+func Rotate180(src *NHSVA) *NHSVA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	return transform(src, src.Rect, func(dx, dy int) (int, int) {
+		return w - 1 - dx, h - 1 - dy
+	})
+}
+
+// Rotate270 returns a copy of an image rotated 270° clockwise (i.e., 90°
+// counterclockwise).  The result's bounds have their origin at (0, 0) and have
+// the source's width and height swapped.
+func Rotate270(src *NHSVA) *NHSVA {
+	w := src.Rect.Dx()
+	return transform(src, image.Rect(0, 0, src.Rect.Dy(), w), func(dx, dy int) (int, int) {
+		return dy, w - 1 - dx
+	})
+}
+
+// FlipHorizontal returns a copy of an image mirrored left to right.  The
+// result has the same bounds as the source.
+func FlipHorizontal(src *NHSVA) *NHSVA {
+	w := src.Rect.Dx()
+	return transform(src, src.Rect, func(dx, dy int) (int, int) {
+		return w - 1 - dx, dy
+	})
+}
+
+// FlipVertical returns a copy of an image mirrored top to bottom.  The result
+// has the same bounds as the source.
+func FlipVertical(src *NHSVA) *NHSVA {
+	h := src.Rect.Dy()
+	return transform(src, src.Rect, func(dx, dy int) (int, int) {
+		return dx, h - 1 - dy
+	})
+}
@@ -0,0 +1,71 @@
+// This file tests geometric transformations of HSV images.
+
+package hsvimage
+
+import (
+	"github.com/spakin/hsvimage/hsvcolor"
+	"image"
+	"testing"
+)
+
+// TestTransforms confirms that each geometric transform moves the corners of
+// a non-square image to the expected positions.
+func TestTransforms(t *testing.T) {
+	// Create a 3×2 image with bounds that do not start at the origin and
+	// distinctly colored corners.
+	tl := hsvcolor.NHSVA{H: 0, S: 255, V: 255, A: 255}
+	tr := hsvcolor.NHSVA{H: 85, S: 255, V: 255, A: 255}
+	bl := hsvcolor.NHSVA{H: 170, S: 255, V: 255, A: 255}
+	br := hsvcolor.NHSVA{H: 42, S: 128, V: 200, A: 100}
+	big := NewNHSVA(image.Rect(0, 0, 10, 10))
+	src := big.SubImage(image.Rect(4, 5, 7, 7)).(*NHSVA)
+	src.SetNHSVA(4, 5, tl)
+	src.SetNHSVA(6, 5, tr)
+	src.SetNHSVA(4, 6, bl)
+	src.SetNHSVA(6, 6, br)
+
+	type corner struct {
+		x, y int
+		c    hsvcolor.NHSVA
+	}
+	for _, tc := range []struct {
+		name    string
+		fn      func(*NHSVA) *NHSVA
+		bounds  image.Rectangle
+		corners []corner
+	}{
+		{"Rotate90", Rotate90, image.Rect(0, 0, 2, 3),
+			[]corner{{1, 0, tl}, {1, 2, tr}, {0, 0, bl}, {0, 2, br}}},
+		{"Rotate180", Rotate180, image.Rect(4, 5, 7, 7),
+			[]corner{{6, 6, tl}, {4, 6, tr}, {6, 5, bl}, {4, 5, br}}},
+		{"Rotate270", Rotate270, image.Rect(0, 0, 2, 3),
+			[]corner{{0, 2, tl}, {0, 0, tr}, {1, 2, bl}, {1, 0, br}}},
+		{"FlipHorizontal", FlipHorizontal, image.Rect(4, 5, 7, 7),
+			[]corner{{6, 5, tl}, {4, 5, tr}, {6, 6, bl}, {4, 6, br}}},
+		{"FlipVertical", FlipVertical, image.Rect(4, 5, 7, 7),
+			[]corner{{4, 6, tl}, {6, 6, tr}, {4, 5, bl}, {6, 5, br}}},
+	} {
+		dst := tc.fn(src)
+		if !dst.Rect.Eq(tc.bounds) {
+			t.Fatalf("%s: Expected bounds %v but saw %v", tc.name, tc.bounds, dst.Rect)
+		}
+		if dst.Stride != 4*tc.bounds.Dx() {
+			t.Fatalf("%s: Expected a stride of %d but saw %d", tc.name, 4*tc.bounds.Dx(), dst.Stride)
+		}
+		for _, c := range tc.corners {
+			if got := dst.NHSVAAt(c.x, c.y); got != c.c {
+				t.Fatalf("%s: Expected %v but saw %v at (%d, %d)", tc.name, c.c, got, c.x, c.y)
+			}
+		}
+	}
+
+	// Rotating four times should restore the original pixels.
+	rot := Rotate90(Rotate90(Rotate90(Rotate90(src))))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			if a, b := src.NHSVAAt(4+x, 5+y), rot.NHSVAAt(x, y); a != b {
+				t.Fatalf("Expected %v but saw %v at (%d, %d)", a, b, x, y)
+			}
+		}
+	}
+}